@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/config"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"gorm.io/gorm"
+)
+
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+const programName = "echo-crud-notes"
+
+// errHelp ends a command that printed its usage because -h was given.
+var errHelp = errors.New("help requested")
+
+// usageError is a command line that can't be run. The command exits with
+// ExitUsage after printing the message and its usage.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func newUsageError(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// command is a node of the command tree. Leaf commands have a setup function
+// that declares their flags and returns the function that runs them, the
+// others only group subcommands.
+type command struct {
+	name     string
+	args     string
+	summary  string
+	setup    func(flags *flag.FlagSet) func(c *CLI, args []string) error
+	commands []*command
+}
+
+type CLI struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Connect opens the database, it is only called by commands that need it.
+	Connect func() (*gorm.DB, error)
+}
+
+func New(stdin io.Reader, stdout io.Writer, stderr io.Writer) *CLI {
+	return &CLI{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+		Connect: func() (*gorm.DB, error) {
+			return database.StartConnection(config.GetDBConfig(true))
+		},
+	}
+}
+
+func commands() *command {
+	return &command{
+		name: programName,
+		commands: []*command{
+			serveCommand(),
+			migrateCommand(),
+			seedCommand(),
+			exportCommand(),
+			importCommand(),
+			userCommand(),
+			configCommand(),
+		},
+	}
+}
+
+// Run runs the command named by args and returns the exit code. Without a
+// command it starts the server.
+func (c *CLI) Run(args []string) int {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+
+	root := commands()
+	cmd, path, rest, err := root.find(args)
+	if errors.Is(err, errHelp) {
+		cmd.printUsage(c.Stdout, path, nil)
+		return ExitOK
+	}
+	if err == nil {
+		err = c.run(cmd, path, rest)
+	}
+
+	var errUsage *usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, errHelp):
+		return ExitOK
+	case errors.As(err, &errUsage):
+		fmt.Fprintf(c.Stderr, "error: %s\n\n", errUsage.message)
+		cmd.printUsage(c.Stderr, path, nil)
+		return ExitUsage
+	default:
+		fmt.Fprintf(c.Stderr, "error: %s\n", errorMessage(err))
+		return ExitError
+	}
+}
+
+// find walks down the tree along args and returns the command, its full name
+// and the remaining arguments.
+func (cmd *command) find(args []string) (*command, string, []string, error) {
+	path := cmd.name
+	for len(cmd.commands) > 0 {
+		if len(args) == 0 {
+			return cmd, path, args, newUsageError("%s needs a command", path)
+		}
+		if isHelp(args[0]) || args[0] == "help" {
+			return cmd, path, args, errHelp
+		}
+
+		var next *command
+		for _, sub := range cmd.commands {
+			if sub.name == args[0] {
+				next = sub
+			}
+		}
+		if next == nil {
+			return cmd, path, args, newUsageError("unknown command %q", args[0])
+		}
+		cmd, path, args = next, path+" "+next.name, args[1:]
+	}
+
+	return cmd, path, args, nil
+}
+
+func (c *CLI) run(cmd *command, path string, args []string) error {
+	flags := flag.NewFlagSet(path, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	run := cmd.setup(flags)
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cmd.printUsage(c.Stdout, path, flags)
+			return errHelp
+		}
+		return &usageError{message: err.Error()}
+	}
+	return run(c, flags.Args())
+}
+
+func (cmd *command) printUsage(w io.Writer, path string, flags *flag.FlagSet) {
+	if len(cmd.commands) > 0 {
+		fmt.Fprintf(w, "usage: %s <command>\n\ncommands:\n", path)
+		for _, sub := range cmd.commands {
+			fmt.Fprintf(w, "  %-16s %s\n", sub.name, sub.summary)
+		}
+		fmt.Fprintf(w, "\nRun %s <command> -h for the flags of a command.\n", path)
+		fmt.Fprintf(w, "Exit codes: %d success, %d failure, %d invalid command line.\n", ExitOK, ExitError, ExitUsage)
+		return
+	}
+
+	fmt.Fprintf(w, "usage: %s", path)
+	if flags == nil {
+		flags = flag.NewFlagSet(path, flag.ContinueOnError)
+		cmd.setup(flags)
+	}
+	hasFlags := false
+	flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprint(w, " [flags]")
+	}
+	if cmd.args != "" {
+		fmt.Fprint(w, " "+cmd.args)
+	}
+	fmt.Fprintf(w, "\n\n%s\n", cmd.summary)
+	if hasFlags {
+		fmt.Fprint(w, "\nflags:\n")
+		flags.SetOutput(w)
+		flags.PrintDefaults()
+		flags.SetOutput(io.Discard)
+	}
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// errorMessage prefers the message the API would answer with, which reads
+// better than the one of a validation error. Unknown errors are printed in
+// full, as the API hides them.
+func errorMessage(err error) string {
+	res := exception.NewErrorResponse(err)
+	if res.Code != http.StatusInternalServerError && res.Message != "" {
+		return res.Message
+	}
+	return err.Error()
+}
+
+// connect opens the database or fails the command.
+func (c *CLI) connect() (*gorm.DB, error) {
+	db, err := c.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to the database: %w", err)
+	}
+	return db, nil
+}
+
+// noArgs fails when a command that takes only flags got arguments.
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return newUsageError("unexpected argument %q", strings.Join(args, " "))
+	}
+	return nil
+}
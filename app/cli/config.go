@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/naomigrain/echo-crud-notes/config"
+)
+
+func configCommand() *command {
+	return &command{
+		name:    "config",
+		summary: "inspect the configuration",
+		commands: []*command{
+			{
+				name:    "print",
+				summary: "print the configuration read from the environment and .env, with secrets masked",
+				setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+					showSecrets := flags.Bool("show-secrets", false, "print secrets instead of masking them")
+					return func(c *CLI, args []string) error {
+						if err := noArgs(args); err != nil {
+							return err
+						}
+						c.printConfig(*showSecrets)
+						return nil
+					}
+				},
+			},
+		},
+	}
+}
+
+// printConfig prints the values the server would use, including defaults, as
+// KEY=value lines.
+func (c *CLI) printConfig(showSecrets bool) {
+	appConfig := config.GetAppConfig(true)
+	dbConfig := config.GetDBConfig(true)
+	secret := func(value string) string {
+		if showSecrets || value == "" {
+			return value
+		}
+		return "********"
+	}
+
+	values := [][2]string{
+		{"APP_PORT", appConfig.AppPort},
+		{"CURSOR_SECRET", secret(appConfig.CursorSecret)},
+		{"JWT_SECRET", secret(appConfig.JWTSecret)},
+		{"ACCESS_TOKEN_TTL", appConfig.AccessTokenTTL.String()},
+		{"REFRESH_TOKEN_TTL", appConfig.RefreshTokenTTL.String()},
+		{"TRASH_RETENTION", appConfig.TrashRetention.String()},
+		{"TRASH_PURGE_INTERVAL", appConfig.TrashPurgeInterval.String()},
+		{"NOTE_BODY_MAX_SIZE", strconv.Itoa(appConfig.NoteBodyMaxSize)},
+		{"REQUEST_TIMEOUT", appConfig.RequestTimeout.String()},
+		{"EXPORT_TIMEOUT", appConfig.ExportTimeout.String()},
+		{"ERROR_FORMAT", appConfig.ErrorFormat},
+		{"DB_DRIVER", dbConfig.DBDriver},
+		{"DB_HOST", dbConfig.DBHost},
+		{"DB_PORT", dbConfig.DBPort},
+		{"DB_NAME", dbConfig.DBName},
+		{"DB_USERNAME", dbConfig.DBUsername},
+		{"DB_PASSWORD", secret(dbConfig.DBPassword)},
+	}
+	for _, value := range values {
+		fmt.Fprintf(c.Stdout, "%s=%s\n", value[0], value[1])
+	}
+}
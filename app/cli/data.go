@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/app/seed"
+	"github.com/naomigrain/echo-crud-notes/config"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/naomigrain/echo-crud-notes/service"
+	"gorm.io/gorm"
+)
+
+func seedCommand() *command {
+	return &command{
+		name:    "seed",
+		summary: "add generated or fixture categories and notes for a user, creating the user when needed",
+		setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+			options := database.SeedOptions{}
+			flags.IntVar(&options.Categories, "categories", 3, "number of categories to generate")
+			flags.IntVar(&options.Notes, "notes", 5, "number of notes to generate")
+			flags.StringVar(&options.Distribution, "distribution", seed.DistributionUniform,
+				"how notes are spread over the categories: "+strings.Join(seed.Distributions, ", "))
+			flags.Int64Var(&options.Seed, "deterministic-seed", 0, "seed of the generated data, 0 picks a random one")
+			flags.StringVar(&options.Fixture, "fixture", "", "load this fixture instead of generating data")
+			flags.StringVar(&options.Email, "email", "demo@example.com", "email of the user that owns the data")
+			flags.StringVar(&options.Password, "password", "password123", "password of the user when it is created")
+			return func(c *CLI, args []string) error {
+				if err := noArgs(args); err != nil {
+					return err
+				}
+				if options.Categories < 0 || options.Notes < 0 {
+					return newUsageError("categories and notes can't be negative")
+				}
+				if !seed.IsDistribution(options.Distribution) {
+					return newUsageError("distribution %q is invalid", options.Distribution)
+				}
+				if options.Fixture != "" {
+					if _, errRead := seed.ReadFixture(seed.Fixtures, options.Fixture); errRead != nil {
+						names, _ := seed.FixtureNames(seed.Fixtures)
+						return newUsageError("%s, the fixtures are %s", errRead, strings.Join(names, ", "))
+					}
+				}
+
+				db, errConn := c.connect()
+				if errConn != nil {
+					return errConn
+				}
+				result, errSeed := database.Seed(db, options)
+				if errSeed != nil {
+					return errSeed
+				}
+				if options.Fixture != "" {
+					fmt.Fprintf(c.Stdout, "seeded %d categories and %d notes for %s from fixture %s\n",
+						len(result.Categories), len(result.Notes), result.User.Email, options.Fixture)
+				} else {
+					fmt.Fprintf(c.Stdout, "seeded %d categories and %d notes for %s with seed %d\n",
+						len(result.Categories), len(result.Notes), result.User.Email, result.Seed)
+				}
+				return nil
+			}
+		},
+	}
+}
+
+func exportCommand() *command {
+	return &command{
+		name:    "export",
+		summary: "export the notes and categories of a user",
+		setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+			email := flags.String("user", "", "email of the user to export (required)")
+			format := flags.String("format", web.FormatJSONL, "jsonl, csv or zip-md")
+			output := flags.String("output", "-", "file to write, - for stdout")
+			return func(c *CLI, args []string) error {
+				if err := noArgs(args); err != nil {
+					return err
+				}
+				if *email == "" {
+					return newUsageError("--user is required")
+				}
+				if !service.IsTransferFormat(*format) {
+					return newUsageError("format %q is invalid", *format)
+				}
+
+				db, userDom, errFind := c.findUser(*email)
+				if errFind != nil {
+					return errFind
+				}
+
+				transferService := newTransferService(db)
+				if *output == "-" {
+					return transferService.Export(context.Background(), userDom.ID, *format, c.Stdout)
+				}
+				file, errCreate := os.Create(*output)
+				if errCreate != nil {
+					return errCreate
+				}
+				if errExport := transferService.Export(context.Background(), userDom.ID, *format, file); errExport != nil {
+					file.Close()
+					return errExport
+				}
+				return file.Close()
+			}
+		},
+	}
+}
+
+func importCommand() *command {
+	return &command{
+		name:    "import",
+		args:    "FILE",
+		summary: "import notes and categories for a user from FILE, - for stdin",
+		setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+			email := flags.String("user", "", "email of the user to import for (required)")
+			request := web.ImportRequest{}
+			flags.StringVar(&request.Format, "format", web.FormatJSONL, "jsonl, csv or zip-md")
+			flags.StringVar(&request.IDs, "ids", web.ImportRemapIDs, "remap or preserve the ids of the file")
+			flags.BoolVar(&request.DryRun, "dry-run", false, "only report what would be imported")
+			return func(c *CLI, args []string) error {
+				if len(args) != 1 {
+					return newUsageError("import needs one file")
+				}
+				if *email == "" {
+					return newUsageError("--user is required")
+				}
+				if !service.IsTransferFormat(request.Format) {
+					return newUsageError("format %q is invalid", request.Format)
+				}
+
+				data, errRead := c.readFile(args[0])
+				if errRead != nil {
+					return errRead
+				}
+				if len(data) > service.ImportMaxSize {
+					return fmt.Errorf("import can be at most %d MB", service.ImportMaxSize>>20)
+				}
+				request.Data = data
+
+				db, userDom, errFind := c.findUser(*email)
+				if errFind != nil {
+					return errFind
+				}
+				report, errImport := newTransferService(db).Import(context.Background(), userDom.ID, request)
+				var errConflict *exception.ConflictError
+				if errImport != nil && !errors.As(errImport, &errConflict) {
+					return errImport
+				}
+
+				encoder := json.NewEncoder(c.Stdout)
+				encoder.SetIndent("", "  ")
+				if errEncode := encoder.Encode(report); errEncode != nil {
+					return errEncode
+				}
+				return errImport
+			}
+		},
+	}
+}
+
+func newTransferService(db *gorm.DB) service.TransferService {
+	txManager := service.NewTxManager(db)
+	validate := helper.NewValidator()
+	if errRegister := service.RegisterValidations(validate, txManager, repository.NewCategoryRepository()); errRegister != nil {
+		panic(errRegister)
+	}
+	return service.NewTransferService(txManager, validate, config.GetAppConfig(true).NoteBodyMaxSize,
+		repository.NewNoteRepositoryImpl(), repository.NewCategoryRepository(),
+		repository.NewNoteRevisionRepository(), repository.NewTagRepository())
+}
+
+func (c *CLI) findUser(email string) (*gorm.DB, domain.User, error) {
+	db, errConn := c.connect()
+	if errConn != nil {
+		return nil, domain.User{}, errConn
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	userDom, errFind := repository.NewUserRepository().FindByEmail(context.Background(), db, email)
+	if errors.Is(errFind, gorm.ErrRecordNotFound) {
+		return db, userDom, fmt.Errorf("user %s does not exist", email)
+	}
+	return db, userDom, errFind
+}
+
+func (c *CLI) readFile(path string) ([]byte, error) {
+	var r io.Reader = c.Stdin
+	if path != "-" {
+		file, errOpen := os.Open(path)
+		if errOpen != nil {
+			return nil, errOpen
+		}
+		defer file.Close()
+		r = file
+	}
+	return io.ReadAll(io.LimitReader(r, service.ImportMaxSize+1))
+}
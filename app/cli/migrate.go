@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+)
+
+func migrateCommand() *command {
+	return &command{
+		name:    "migrate",
+		summary: "apply, revert, list and create database migrations",
+		commands: []*command{
+			{
+				name:    "up",
+				summary: "apply all pending migrations",
+				setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+					return func(c *CLI, args []string) error {
+						if err := noArgs(args); err != nil {
+							return err
+						}
+						return c.migrateUp()
+					}
+				},
+			},
+			{
+				name:    "down",
+				args:    "[N]",
+				summary: "revert the last N migrations, 1 by default",
+				setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+					return func(c *CLI, args []string) error {
+						n := 1
+						if len(args) > 1 {
+							return newUsageError("migrate down takes at most one number")
+						}
+						if len(args) == 1 {
+							var errParse error
+							if n, errParse = strconv.Atoi(args[0]); errParse != nil || n < 1 {
+								return newUsageError("migrate down needs a positive number, got %q", args[0])
+							}
+						}
+						return c.migrateDown(n)
+					}
+				},
+			},
+			{
+				name:    "status",
+				summary: "list migrations and when they were applied",
+				setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+					return func(c *CLI, args []string) error {
+						if err := noArgs(args); err != nil {
+							return err
+						}
+						return c.migrateStatus()
+					}
+				},
+			},
+			{
+				name:    "create",
+				args:    "NAME",
+				summary: "add an empty migration to " + database.MigrationsDir,
+				setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+					return func(c *CLI, args []string) error {
+						if len(args) == 0 {
+							return newUsageError("migrate create needs a name")
+						}
+						paths, errCreate := database.CreateMigration(database.MigrationsDir, strings.Join(args, "_"))
+						if errCreate != nil {
+							return errCreate
+						}
+						fmt.Fprintln(c.Stdout, strings.Join(paths, "\n"))
+						return nil
+					}
+				},
+			},
+		},
+	}
+}
+
+func (c *CLI) migrator() (*database.Migrator, error) {
+	db, errConn := c.connect()
+	if errConn != nil {
+		return nil, errConn
+	}
+	return database.NewMigrator(db, database.Migrations)
+}
+
+func (c *CLI) migrateUp() error {
+	migrator, errLoad := c.migrator()
+	if errLoad != nil {
+		return errLoad
+	}
+
+	applied, errUp := migrator.Up()
+	for _, migration := range applied {
+		fmt.Fprintf(c.Stdout, "applied %04d_%s\n", migration.Version, migration.Name)
+	}
+	if errUp != nil {
+		return errUp
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(c.Stdout, "nothing to migrate")
+	}
+	return nil
+}
+
+func (c *CLI) migrateDown(n int) error {
+	migrator, errLoad := c.migrator()
+	if errLoad != nil {
+		return errLoad
+	}
+
+	reverted, errDown := migrator.Down(n)
+	for _, migration := range reverted {
+		fmt.Fprintf(c.Stdout, "reverted %04d_%s\n", migration.Version, migration.Name)
+	}
+	return errDown
+}
+
+func (c *CLI) migrateStatus() error {
+	migrator, errLoad := c.migrator()
+	if errLoad != nil {
+		return errLoad
+	}
+
+	statuses, errStatus := migrator.Status()
+	if errStatus != nil {
+		return errStatus
+	}
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Missing {
+			state += " (files missing)"
+		}
+		fmt.Fprintf(c.Stdout, "%04d_%-30s %s\n", status.Version, status.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/app/router"
+	"github.com/naomigrain/echo-crud-notes/config"
+	"github.com/naomigrain/echo-crud-notes/helper"
+)
+
+// shutdownTimeout is how long requests in flight get to finish on SIGINT or
+// SIGTERM.
+const shutdownTimeout = 10 * time.Second
+
+func serveCommand() *command {
+	return &command{
+		name:    "serve",
+		summary: "start the API server, the default command",
+		setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+			port := flags.String("port", "", "port to listen on (default APP_PORT)")
+			return func(c *CLI, args []string) error {
+				if err := noArgs(args); err != nil {
+					return err
+				}
+				return c.serve(*port)
+			}
+		},
+	}
+}
+
+// errNoJWTSecret stops serve: with a random key every restart logs all users
+// out and instances behind a load balancer reject each other's tokens.
+var errNoJWTSecret = errors.New("JWT_SECRET is not set, tokens would not survive a restart or verify on other instances")
+
+func (c *CLI) serve(port string) error {
+	appConfig := config.GetAppConfig(true)
+	if appConfig.JWTSecret == "" {
+		return errNoJWTSecret
+	}
+	if appConfig.CursorSecret == "" {
+		fmt.Fprintln(c.Stderr, "warning: CURSOR_SECRET is not set, page cursors won't survive a restart")
+	}
+
+	db, errConn := c.connect()
+	if errConn != nil {
+		return errConn
+	}
+	if migrator, errLoad := database.NewMigrator(db, database.Migrations); errLoad == nil {
+		if pending, _ := migrator.Pending(); pending > 0 {
+			fmt.Fprintf(c.Stderr, "%d migrations are pending, run migrate up\n", pending)
+		}
+	}
+
+	if port == "" {
+		port = appConfig.AppPort
+	}
+	e := router.InitializeEcho(appConfig)
+	router.AssignRouter(e, db, helper.NewValidator(), appConfig)
+
+	stopPurger := database.StartTrashPurger(db, appConfig.TrashRetention, appConfig.TrashPurgeInterval)
+	defer stopPurger()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errServe := make(chan error, 1)
+	go func() {
+		errServe <- e.Start(":" + port)
+	}()
+
+	select {
+	case err := <-errServe:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errServe; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"strings"
+
+	"github.com/naomigrain/echo-crud-notes/config"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+func userCommand() *command {
+	return &command{
+		name:    "user",
+		summary: "manage users",
+		commands: []*command{
+			{
+				name:    "create",
+				summary: "add a user with any role, the password is read from stdin when --password is empty",
+				setup: func(flags *flag.FlagSet) func(c *CLI, args []string) error {
+					request := web.CreateUserRequest{}
+					flags.StringVar(&request.Email, "email", "", "email of the user (required)")
+					flags.StringVar(&request.Password, "password", "", "password of the user")
+					flags.StringVar(&request.Role, "role", domain.RoleEditor, "admin, editor or viewer")
+					return func(c *CLI, args []string) error {
+						if err := noArgs(args); err != nil {
+							return err
+						}
+						if request.Email == "" {
+							return newUsageError("--email is required")
+						}
+						if request.Password == "" {
+							line, _ := bufio.NewReader(c.Stdin).ReadString('\n')
+							request.Password = strings.TrimRight(line, "\r\n")
+						}
+						return c.createUser(request)
+					}
+				},
+			},
+		},
+	}
+}
+
+func (c *CLI) createUser(request web.CreateUserRequest) error {
+	db, errConn := c.connect()
+	if errConn != nil {
+		return errConn
+	}
+
+	appConfig := config.GetAppConfig(true)
+	tokens := helper.NewTokenManager(appConfig.JWTSecret, appConfig.AccessTokenTTL, appConfig.RefreshTokenTTL)
+	authService := service.NewAuthService(service.NewTxManager(db), helper.NewValidator(), repository.NewUserRepository(), tokens)
+	userResponse, errCreate := authService.CreateUser(context.Background(), request)
+	if errCreate != nil {
+		return errCreate
+	}
+
+	encoder := json.NewEncoder(c.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(userResponse)
+}
@@ -1,13 +1,16 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math/rand"
+	"time"
 
+	"github.com/naomigrain/echo-crud-notes/app/seed"
 	"github.com/naomigrain/echo-crud-notes/config"
-	"github.com/naomigrain/echo-crud-notes/helper"
 	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"github.com/naomigrain/echo-crud-notes/repository"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -22,61 +25,113 @@ func StartConnection(dbConfig config.DBConfig) (*gorm.DB, error) {
 	return db, err
 }
 
-func Migrate(db *gorm.DB) {
-	db.Migrator().CreateTable(&domain.Category{})
-	db.Migrator().CreateTable(&domain.Note{})
-}
-
+// DropAll removes every table including the migration history, so the next
+// migrate up starts from an empty database.
 func DropAll(db *gorm.DB) {
+	db.Migrator().DropTable(&domain.NoteTag{})
+	db.Migrator().DropTable(&domain.NoteRevision{})
 	db.Migrator().DropTable(&domain.Note{})
+	db.Migrator().DropTable(&domain.Tag{})
 	db.Migrator().DropTable(&domain.Category{})
+	db.Migrator().DropTable(&domain.APIKey{})
+	db.Migrator().DropTable(&domain.User{})
+	db.Migrator().DropTable(&schemaMigration{})
 }
 
-func CategorySeeder(db *gorm.DB, numRecords int) []domain.Category {
-	categoryRepository := repository.NewCategoryRepository()
-	var categoryList []domain.Category
-	for i := 0; i < numRecords; i++ {
-		categoryList = append(categoryList, domain.Category{
-			Name: "Category " + helper.RandomString(rand.Intn(80)),
-		})
+type SeedOptions struct {
+	Email        string
+	Password     string
+	Categories   int
+	Notes        int
+	Distribution string
+	// Fixture loads the named fixture instead of generating data.
+	Fixture string
+	// Seed makes the generated data repeatable, 0 picks a random one.
+	Seed int64
+}
+
+type SeedResult struct {
+	seed.Result
+	User domain.User
+	Seed int64
+}
+
+// Seed adds categories and notes for the user with the given email, creating
+// the user as an admin when it doesn't exist yet.
+func Seed(db *gorm.DB, options SeedOptions) (SeedResult, error) {
+	result := SeedResult{Seed: options.Seed}
+	if result.Seed == 0 {
+		result.Seed = time.Now().UnixNano()
 	}
-	for i, c := range categoryList {
-		tx := db.Begin()
-		categoryDom, _ := categoryRepository.Save(tx, c)
-		tx.Commit()
-		categoryList[i].ID = categoryDom.ID
+
+	userDom, errFind := repository.NewUserRepository().FindByEmail(context.Background(), db, options.Email)
+	if errFind != nil {
+		if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return result, errFind
+		}
+		if userDom = UserSeeder(db, options.Email, options.Password, domain.RoleAdmin); userDom.ID == 0 {
+			return result, fmt.Errorf("user %s can not be created", options.Email)
+		}
 	}
-	return categoryList
-}
+	result.User = userDom
 
-func NoteSeeder(db *gorm.DB, categoryList []domain.Category, numRecords int) []domain.Note {
-	noteRepository := repository.NewNoteRepositoryImpl()
-	var noteList []domain.Note
-
-	for i := 0; i < numRecords; i++ {
-		tx := db.Begin()
-		noteDom, _ := noteRepository.Save(tx, domain.Note{
-			Title:      "Category " + helper.RandomString(rand.Intn(80)),
-			Body:       "Body " + helper.RandomString(rand.Intn(100)),
-			CategoryID: categoryList[rand.Intn(len(categoryList))].ID,
+	var errSeed error
+	if options.Fixture != "" {
+		result.Result, errSeed = seed.LoadFixture(db, seed.Fixtures, options.Fixture, userDom.ID)
+	} else {
+		result.Result, errSeed = seed.Run(db, userDom.ID, seed.Options{
+			Categories:   options.Categories,
+			Notes:        options.Notes,
+			Distribution: options.Distribution,
+			Seed:         result.Seed,
 		})
-		noteList = append(noteList, noteDom)
-		tx.Commit()
 	}
 
+	return result, errSeed
+}
+
+func UserSeeder(db *gorm.DB, email string, password string, role string) domain.User {
+	userRepository := repository.NewUserRepository()
+	hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	tx := db.Begin()
+	userDom, _ := userRepository.Save(context.Background(), tx, domain.User{
+		Email:    email,
+		Password: string(hash),
+		Role:     role,
+	})
+	tx.Commit()
+
+	return userDom
+}
+
+func CategorySeeder(db *gorm.DB, userID int, numRecords int) []domain.Category {
+	categoryList, _ := seed.Categories(db, seed.NewGenerator(time.Now().UnixNano()), userID, numRecords)
+	return categoryList
+}
+
+// NoteSeeder spreads the notes evenly over categoryList, in order.
+func NoteSeeder(db *gorm.DB, categoryList []domain.Category, numRecords int) []domain.Note {
+	noteList, _ := seed.Notes(db, seed.NewGenerator(time.Now().UnixNano()), categoryList, numRecords,
+		seed.DistributionUniform)
 	return noteList
 }
 
 func DeleteCategoryRecords(db *gorm.DB) {
-	db.Where("1=1").Delete(&domain.Category{})
+	db.Unscoped().Where("1=1").Delete(&domain.Category{})
 }
 
 func DeleteNoteRecords(db *gorm.DB) {
-	db.Where("1=1").Delete(&domain.Note{})
+	db.Unscoped().Where("1=1").Delete(&domain.Note{})
+}
+
+func DeleteTagRecords(db *gorm.DB) {
+	db.Where("1=1").Delete(&domain.Tag{})
 }
 
 func DeleteAllRecords(db *gorm.DB) {
 	fmt.Println("Delete all records...")
 	DeleteNoteRecords(db)
 	DeleteCategoryRecords(db)
+	DeleteTagRecords(db)
 }
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS note_revisions;
+DROP TABLE IF EXISTS note_tags;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,100 @@
+-- The schema as it was created by GORM before migrations existed. Every
+-- statement is guarded, so databases from that time are adopted as they are
+-- and only get the columns that were added to notes since.
+CREATE TABLE IF NOT EXISTS users (
+	id bigserial PRIMARY KEY,
+	email varchar(255) NOT NULL,
+	password varchar(255) NOT NULL,
+	role varchar(20) NOT NULL DEFAULT 'editor',
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id bigserial PRIMARY KEY,
+	name varchar(100) NOT NULL,
+	prefix varchar(16) NOT NULL,
+	key_hash char(64) NOT NULL,
+	scopes varchar(255) NOT NULL,
+	user_id bigint NOT NULL,
+	last_used_at timestamptz,
+	revoked_at timestamptz,
+	created_at timestamptz,
+	updated_at timestamptz,
+	CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+
+CREATE TABLE IF NOT EXISTS categories (
+	id bigserial PRIMARY KEY,
+	name varchar(100) NOT NULL,
+	parent_id bigint,
+	user_id bigint NOT NULL,
+	version bigint NOT NULL DEFAULT 1,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	CONSTRAINT fk_categories_parent FOREIGN KEY (parent_id) REFERENCES categories (id) ON DELETE SET NULL,
+	CONSTRAINT fk_categories_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+CREATE INDEX IF NOT EXISTS idx_categories_user_id ON categories (user_id);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+	id bigserial PRIMARY KEY,
+	name varchar(50) NOT NULL,
+	user_id bigint NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz,
+	CONSTRAINT fk_tags_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (name, user_id);
+
+CREATE TABLE IF NOT EXISTS notes (
+	id bigserial PRIMARY KEY,
+	title varchar(100) NOT NULL,
+	body text NOT NULL,
+	format varchar(16) NOT NULL DEFAULT 'plain',
+	category_id bigint,
+	user_id bigint NOT NULL,
+	version bigint NOT NULL DEFAULT 1,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	CONSTRAINT fk_notes_category FOREIGN KEY (category_id) REFERENCES categories (id),
+	CONSTRAINT fk_notes_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_notes_user_id ON notes (user_id);
+CREATE INDEX IF NOT EXISTS idx_notes_deleted_at ON notes (deleted_at);
+ALTER TABLE notes ALTER COLUMN body TYPE text;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS format varchar(16) NOT NULL DEFAULT 'plain';
+CREATE INDEX IF NOT EXISTS idx_notes_search ON notes
+	USING GIN (to_tsvector('english', title || ' ' || body));
+
+CREATE TABLE IF NOT EXISTS note_tags (
+	note_id bigint NOT NULL,
+	tag_id bigint NOT NULL,
+	PRIMARY KEY (note_id, tag_id),
+	CONSTRAINT fk_note_tags_note FOREIGN KEY (note_id) REFERENCES notes (id) ON DELETE CASCADE,
+	CONSTRAINT fk_note_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_note_tags_tag_id ON note_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS note_revisions (
+	id bigserial PRIMARY KEY,
+	note_id bigint NOT NULL,
+	revision bigint NOT NULL,
+	title varchar(100) NOT NULL,
+	body text NOT NULL,
+	format varchar(16) NOT NULL DEFAULT 'plain',
+	category_id bigint NOT NULL,
+	author_id bigint NOT NULL,
+	created_at timestamptz,
+	CONSTRAINT fk_note_revisions_note FOREIGN KEY (note_id) REFERENCES notes (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_note_revisions_note_revision ON note_revisions (note_id, revision);
+ALTER TABLE note_revisions ALTER COLUMN body TYPE text;
+ALTER TABLE note_revisions ADD COLUMN IF NOT EXISTS format varchar(16) NOT NULL DEFAULT 'plain';
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"gorm.io/gorm"
+)
+
+// StartTrashPurger permanently deletes notes and categories that have been in
+// the trash for longer than retention, checking every interval until the
+// returned stop function is called. Stopping also cancels a running purge.
+func StartTrashPurger(db *gorm.DB, retention time.Duration, interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			PurgeTrash(ctx, db, time.Now().Add(-retention))
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return cancel
+}
+
+func PurgeTrash(ctx context.Context, db *gorm.DB, before time.Time) {
+	noteRepository := repository.NewNoteRepositoryImpl()
+	categoryRepository := repository.NewCategoryRepository()
+
+	tx := db.WithContext(ctx).Begin()
+	notes, errNotes := noteRepository.PurgeTrashed(ctx, tx, before)
+	if errNotes != nil {
+		tx.Rollback()
+		log.Printf("purge trash: %v", errNotes)
+		return
+	}
+	categories, errCategories := categoryRepository.PurgeTrashed(ctx, tx, before)
+	if errCategories != nil {
+		tx.Rollback()
+		log.Printf("purge trash: %v", errCategories)
+		return
+	}
+	if errCommit := tx.Commit().Error; errCommit != nil {
+		log.Printf("purge trash: %v", errCommit)
+		return
+	}
+
+	if notes > 0 || categories > 0 {
+		log.Printf("purge trash: removed %d notes and %d categories", notes, categories)
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+const HeaderAPIKey = "X-API-Key"
+
+// JWTAuth only accepts access tokens issued to a logged in user.
+func JWTAuth(tokens *helper.TokenManager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, found := bearerToken(c)
+			if !found {
+				return &exception.UnauthorizedError{Message: "missing bearer token"}
+			}
+
+			if errAuth := authenticateJWT(c, tokens, token); errAuth != nil {
+				return errAuth
+			}
+			return next(c)
+		}
+	}
+}
+
+// Authenticate accepts either a user access token or an API key, sent as
+// "Authorization: Bearer <key>" or in the X-API-Key header. Requests made with
+// an API key are limited to the key's scopes, see RequireScope.
+func Authenticate(tokens *helper.TokenManager, apiKeys service.APIKeyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderAPIKey)
+			token, found := bearerToken(c)
+			if key == "" && found && strings.HasPrefix(token, service.APIKeyPrefix) {
+				key = token
+			}
+
+			if key != "" {
+				apiKey, errAuth := apiKeys.Authenticate(c.Request().Context(), key)
+				if errAuth != nil {
+					return errAuth
+				}
+				c.Set(helper.UserIDContextKey, apiKey.UserID)
+				c.Set(helper.RoleContextKey, apiKey.User.Role)
+				c.Set(helper.ScopesContextKey, strings.Split(apiKey.Scopes, ","))
+				return next(c)
+			}
+
+			if !found {
+				return &exception.UnauthorizedError{Message: "missing bearer token"}
+			}
+			if errAuth := authenticateJWT(c, tokens, token); errAuth != nil {
+				return errAuth
+			}
+			return next(c)
+		}
+	}
+}
+
+func bearerToken(c echo.Context) (string, bool) {
+	scheme, token, found := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func authenticateJWT(c echo.Context, tokens *helper.TokenManager, token string) error {
+	claims, errParse := tokens.Parse(token, helper.AccessToken)
+	if errParse != nil {
+		return &exception.UnauthorizedError{Message: errParse.Error()}
+	}
+
+	c.Set(helper.UserIDContextKey, claims.UserID)
+	c.Set(helper.RoleContextKey, claims.Role)
+	return nil
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+)
+
+var roleRank = map[string]int{
+	domain.RoleViewer: 1,
+	domain.RoleEditor: 2,
+	domain.RoleAdmin:  3,
+}
+
+// RequireRole only lets the request through when the authenticated user holds
+// the given role or a stronger one (admin > editor > viewer).
+func RequireRole(role string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if roleRank[helper.GetRole(c)] < roleRank[role] {
+				return &exception.ForbiddenError{Message: "requires " + role + " role"}
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireScope is enforced on API key requests only; a logged in user is not
+// limited by scopes.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scopes, isAPIKey := helper.GetScopes(c)
+			if isAPIKey && !slices.Contains(scopes, scope) {
+				return &exception.ForbiddenError{Message: "api key is missing scope " + scope}
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/app/middleware"
+	"github.com/naomigrain/echo-crud-notes/controller"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+func APIKeyRouter(e *echo.Echo, mainUrl string, service service.APIKeyService, auth echo.MiddlewareFunc) {
+	controller := controller.NewAPIKeyController(service)
+
+	g := e.Group(mainUrl+"/keys", auth, middleware.RequireRole(domain.RoleViewer))
+	g.GET("", controller.GetAll)
+	g.POST("", controller.Create)
+	g.POST("/:id/rotate", controller.Rotate)
+	g.DELETE("/:id", controller.Revoke)
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/controller"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+func AuthRouter(e *echo.Echo, mainUrl string, txManager service.TxManager, validate *validator.Validate,
+	tokens *helper.TokenManager) {
+	repository := repository.NewUserRepository()
+	service := service.NewAuthService(txManager, validate, repository, tokens)
+	controller := controller.NewAuthController(service)
+
+	g := e.Group(mainUrl + "/auth")
+	g.POST("/register", controller.Register)
+	g.POST("/login", controller.Login)
+	g.POST("/refresh", controller.Refresh)
+}
@@ -3,21 +3,34 @@ package router
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/app/middleware"
 	"github.com/naomigrain/echo-crud-notes/controller"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"github.com/naomigrain/echo-crud-notes/repository"
 	"github.com/naomigrain/echo-crud-notes/service"
-	"gorm.io/gorm"
 )
 
-func CategoryRouter(e *echo.Echo, mainUrl string, db *gorm.DB, validate *validator.Validate) {
+func CategoryRouter(e *echo.Echo, mainUrl string, txManager service.TxManager, validate *validator.Validate,
+	cursor *helper.CursorCodec, auth echo.MiddlewareFunc) {
+	noteRepository := repository.NewNoteRepositoryImpl()
 	repository := repository.NewCategoryRepository()
-	service := service.NewCategoryService(db, validate, repository)
-	controller := controller.NewCategoryController(service)
+	service := service.NewCategoryService(txManager, validate, repository, noteRepository)
+	controller := controller.NewCategoryController(service, cursor)
 
-	g := e.Group(mainUrl + "/categories")
-	g.GET("", controller.GetAll)
-	g.GET("/:id", controller.GetById)
-	g.POST("", controller.Create)
-	g.PUT("/:id", controller.Update)
-	g.DELETE("/:id", controller.Delete)
+	read := []echo.MiddlewareFunc{
+		middleware.RequireRole(domain.RoleViewer), middleware.RequireScope(domain.ScopeCategoriesRead),
+	}
+	write := []echo.MiddlewareFunc{
+		middleware.RequireRole(domain.RoleEditor), middleware.RequireScope(domain.ScopeCategoriesWrite),
+	}
+
+	g := e.Group(mainUrl+"/categories", auth)
+	g.GET("", controller.GetAll, read...)
+	g.GET("/tree", controller.GetTree, read...)
+	g.GET("/:id", controller.GetById, read...)
+	g.GET("/:id/descendants", controller.GetDescendants, read...)
+	g.POST("", controller.Create, write...)
+	g.PUT("/:id", controller.Update, write...)
+	g.DELETE("/:id", controller.Delete, append(write, middleware.RequireRole(domain.RoleAdmin))...)
 }
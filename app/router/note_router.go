@@ -3,22 +3,44 @@ package router
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/app/middleware"
 	"github.com/naomigrain/echo-crud-notes/controller"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"github.com/naomigrain/echo-crud-notes/repository"
 	"github.com/naomigrain/echo-crud-notes/service"
-	"gorm.io/gorm"
 )
 
-func NoteRouter(e *echo.Echo, mainUrl string, db *gorm.DB, validate *validator.Validate) {
+func NoteRouter(e *echo.Echo, mainUrl string, txManager service.TxManager, validate *validator.Validate,
+	noteBodyMaxSize int, cursor *helper.CursorCodec, auth echo.MiddlewareFunc) {
 	categoryRepository := repository.NewCategoryRepository()
 	noteRepository := repository.NewNoteRepositoryImpl()
-	service := service.NewNoteRepositoryImpl(db, validate, noteRepository, categoryRepository)
-	controller := controller.NewNoteController(service)
+	revisionRepository := repository.NewNoteRevisionRepository()
+	noteService := service.NewNoteRepositoryImpl(txManager, validate, noteBodyMaxSize, noteRepository, categoryRepository,
+		revisionRepository, repository.NewTagRepository())
+	revisionService := service.NewNoteRevisionService(txManager, noteRepository, categoryRepository, revisionRepository)
+	revisionController := controller.NewNoteRevisionController(revisionService)
+	controller := controller.NewNoteController(noteService, cursor)
 
-	g := e.Group(mainUrl + "/notes")
-	g.GET("", controller.GetAll)
-	g.GET("/:id", controller.GetById)
-	g.POST("", controller.Create)
-	g.PUT("/:id", controller.Update)
-	g.DELETE("/:id", controller.Delete)
+	read := []echo.MiddlewareFunc{
+		middleware.RequireRole(domain.RoleViewer), middleware.RequireScope(domain.ScopeNotesRead),
+	}
+	write := []echo.MiddlewareFunc{
+		middleware.RequireRole(domain.RoleEditor), middleware.RequireScope(domain.ScopeNotesWrite),
+	}
+
+	g := e.Group(mainUrl+"/notes", auth)
+	g.GET("", controller.GetAll, read...)
+	g.GET("/trash", controller.GetTrash, read...)
+	g.GET("/:id", controller.GetById, read...)
+	g.POST("", controller.Create, write...)
+	g.POST("/batch", controller.Batch, write...)
+	g.PUT("/:id", controller.Update, write...)
+	g.DELETE("/:id", controller.Delete, write...)
+	g.POST("/:id/restore", controller.Restore, write...)
+	g.DELETE("/:id/purge", controller.Purge, write...)
+	g.GET("/:id/revisions", revisionController.GetAll, read...)
+	g.GET("/:id/revisions/diff", revisionController.Diff, read...)
+	g.GET("/:id/revisions/:rev", revisionController.GetByRevision, read...)
+	g.POST("/:id/revisions/:rev/restore", revisionController.Restore, write...)
 }
@@ -4,25 +4,51 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	appMiddleware "github.com/naomigrain/echo-crud-notes/app/middleware"
+	"github.com/naomigrain/echo-crud-notes/config"
 	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/naomigrain/echo-crud-notes/service"
 	"gorm.io/gorm"
 )
 
-func InitializeEcho() *echo.Echo {
+func InitializeEcho(appConfig *config.AppConfig) *echo.Echo {
 	e := echo.New()
+	e.Use(middleware.RequestID())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Pre(middleware.RemoveTrailingSlash())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
+		AllowOrigins:  []string{"*"},
+		ExposeHeaders: []string{helper.HeaderETag, echo.HeaderXRequestID},
 	}))
-	e.HTTPErrorHandler = exception.CustomErrorHandler
+	e.HTTPErrorHandler = exception.NewErrorHandler(appConfig.ErrorFormat)
 
 	return e
 }
 
-func AssignRouter(e *echo.Echo, db *gorm.DB, validate *validator.Validate) {
+func AssignRouter(e *echo.Echo, db *gorm.DB, validate *validator.Validate, appConfig *config.AppConfig) {
 	mainUrl := "/api"
-	CategoryRouter(e, mainUrl, db, validate)
-	NoteRouter(e, mainUrl, db, validate)
+	txManager := service.NewTxManager(db)
+	if errRegister := service.RegisterValidations(validate, txManager, repository.NewCategoryRepository()); errRegister != nil {
+		panic(errRegister)
+	}
+	cursor := helper.NewCursorCodec(appConfig.CursorSecret)
+	tokens := helper.NewTokenManager(appConfig.JWTSecret, appConfig.AccessTokenTTL, appConfig.RefreshTokenTTL)
+	apiKeyService := service.NewAPIKeyService(txManager, validate, repository.NewAPIKeyRepository())
+	auth := appMiddleware.Authenticate(tokens, apiKeyService)
+	// Streamed responses outlast ordinary requests, their routes set a deadline
+	// of their own.
+	streaming := map[string]bool{mainUrl + "/export": true}
+	e.Use(appMiddleware.Timeout(appConfig.RequestTimeout, func(c echo.Context) bool {
+		return streaming[c.Path()]
+	}))
+
+	AuthRouter(e, mainUrl, txManager, validate, tokens)
+	APIKeyRouter(e, mainUrl, apiKeyService, appMiddleware.JWTAuth(tokens))
+	CategoryRouter(e, mainUrl, txManager, validate, cursor, auth)
+	NoteRouter(e, mainUrl, txManager, validate, appConfig.NoteBodyMaxSize, cursor, auth)
+	TagRouter(e, mainUrl, txManager, validate, auth)
+	TransferRouter(e, mainUrl, txManager, validate, appConfig.NoteBodyMaxSize, appConfig.ExportTimeout, auth)
 }
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/app/middleware"
+	"github.com/naomigrain/echo-crud-notes/controller"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+// TagRouter mounts /tags. Tags only label notes, so API keys need the note
+// scopes to use them.
+func TagRouter(e *echo.Echo, mainUrl string, txManager service.TxManager, validate *validator.Validate, auth echo.MiddlewareFunc) {
+	repository := repository.NewTagRepository()
+	service := service.NewTagService(txManager, validate, repository)
+	controller := controller.NewTagController(service)
+
+	read := []echo.MiddlewareFunc{
+		middleware.RequireRole(domain.RoleViewer), middleware.RequireScope(domain.ScopeNotesRead),
+	}
+	write := []echo.MiddlewareFunc{
+		middleware.RequireRole(domain.RoleEditor), middleware.RequireScope(domain.ScopeNotesWrite),
+	}
+
+	g := e.Group(mainUrl+"/tags", auth)
+	g.GET("", controller.GetAll, read...)
+	g.GET("/:id", controller.GetById, read...)
+	g.POST("", controller.Create, write...)
+	g.PUT("/:id", controller.Update, write...)
+	g.DELETE("/:id", controller.Delete, write...)
+}
@@ -0,0 +1,30 @@
+package router
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/app/middleware"
+	"github.com/naomigrain/echo-crud-notes/controller"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+// TransferRouter mounts /export and /import, which cover both notes and
+// categories, so API keys need the scopes of both. Exports stream and run
+// under exportTimeout instead of the timeout of other requests.
+func TransferRouter(e *echo.Echo, mainUrl string, txManager service.TxManager, validate *validator.Validate, noteBodyMaxSize int,
+	exportTimeout time.Duration, auth echo.MiddlewareFunc) {
+	service := service.NewTransferService(txManager, validate, noteBodyMaxSize, repository.NewNoteRepositoryImpl(),
+		repository.NewCategoryRepository(), repository.NewNoteRevisionRepository(), repository.NewTagRepository())
+	controller := controller.NewTransferController(service)
+
+	e.GET(mainUrl+"/export", controller.Export, middleware.Timeout(exportTimeout, nil), auth,
+		middleware.RequireRole(domain.RoleViewer),
+		middleware.RequireScope(domain.ScopeNotesRead), middleware.RequireScope(domain.ScopeCategoriesRead))
+	e.POST(mainUrl+"/import", controller.Import, auth,
+		middleware.RequireRole(domain.RoleEditor),
+		middleware.RequireScope(domain.ScopeNotesWrite), middleware.RequireScope(domain.ScopeCategoriesWrite))
+}
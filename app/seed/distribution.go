@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Distributions of the notes over the categories.
+const (
+	// DistributionUniform gives every category the same number of notes, give
+	// or take one.
+	DistributionUniform = "uniform"
+	// DistributionRandom puts every note in a random category.
+	DistributionRandom = "random"
+	// DistributionZipf fills the first categories the most, like real data
+	// where a few categories hold most of the notes.
+	DistributionZipf = "zipf"
+)
+
+var Distributions = []string{DistributionUniform, DistributionRandom, DistributionZipf}
+
+func IsDistribution(distribution string) bool {
+	for _, d := range Distributions {
+		if d == distribution {
+			return true
+		}
+	}
+	return false
+}
+
+// assign returns the index of the category of each note.
+func assign(random *rand.Rand, distribution string, notes int, categories int) ([]int, error) {
+	if !IsDistribution(distribution) {
+		return nil, fmt.Errorf("distribution is one of %s", strings.Join(Distributions, ", "))
+	}
+	if notes > 0 && categories == 0 {
+		return nil, fmt.Errorf("%d notes need at least one category", notes)
+	}
+
+	indexes := make([]int, notes)
+	switch distribution {
+	case DistributionUniform:
+		for i := range indexes {
+			indexes[i] = i % categories
+		}
+	case DistributionRandom:
+		for i := range indexes {
+			indexes[i] = random.Intn(categories)
+		}
+	case DistributionZipf:
+		if notes == 0 {
+			break
+		}
+		zipf := rand.NewZipf(random, 1.5, 1, uint64(categories-1))
+		for i := range indexes {
+			indexes[i] = int(zipf.Uint64())
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,211 @@
+package seed
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"unicode/utf8"
+
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/naomigrain/echo-crud-notes/service"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+//go:embed fixtures
+var embeddedFixtures embed.FS
+
+// Fixtures holds the fixtures that are built into the binary.
+var Fixtures, _ = fs.Sub(embeddedFixtures, "fixtures")
+
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+// Fixture is a fixed set of categories and notes. Categories and notes refer
+// to their category by name, and a parent has to come before its children.
+type Fixture struct {
+	Categories []FixtureCategory `json:"categories" yaml:"categories"`
+	Notes      []FixtureNote     `json:"notes" yaml:"notes"`
+}
+
+type FixtureCategory struct {
+	Name   string `json:"name" yaml:"name"`
+	Parent string `json:"parent" yaml:"parent"`
+}
+
+type FixtureNote struct {
+	Title    string   `json:"title" yaml:"title"`
+	Body     string   `json:"body" yaml:"body"`
+	Format   string   `json:"format" yaml:"format"`
+	Category string   `json:"category" yaml:"category"`
+	Tags     []string `json:"tags" yaml:"tags"`
+}
+
+// ReadFixture reads the fixture called name from fsys, which is the file
+// name.yaml, name.yml or name.json at its root.
+func ReadFixture(fsys fs.FS, name string) (Fixture, error) {
+	var fixture Fixture
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		data, errRead := fs.ReadFile(fsys, name+ext)
+		if errors.Is(errRead, fs.ErrNotExist) {
+			continue
+		}
+		if errRead != nil {
+			return fixture, errRead
+		}
+
+		var errDecode error
+		if ext == ".json" {
+			errDecode = json.Unmarshal(data, &fixture)
+		} else {
+			errDecode = yaml.Unmarshal(data, &fixture)
+		}
+		if errDecode != nil {
+			return fixture, fmt.Errorf("fixture %s: %w", name+ext, errDecode)
+		}
+		if errCheck := fixture.check(); errCheck != nil {
+			return fixture, fmt.Errorf("fixture %s: %w", name+ext, errCheck)
+		}
+		return fixture, nil
+	}
+
+	return fixture, fmt.Errorf("%w: %s", ErrFixtureNotFound, name)
+}
+
+// FixtureNames lists the fixtures at the root of fsys.
+func FixtureNames(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		switch ext := path.Ext(entry.Name()); ext {
+		case ".yaml", ".yml", ".json":
+			names = append(names, entry.Name()[:len(entry.Name())-len(ext)])
+		}
+	}
+	return names, nil
+}
+
+// check applies the limits the API would, so a fixture can't hold data that
+// can't be created through it.
+func (f *Fixture) check() error {
+	categories := map[string]bool{}
+	for i, category := range f.Categories {
+		if length := utf8.RuneCountInString(category.Name); length < 2 || length > NameMaxLength {
+			return fmt.Errorf("category %d: name must have 2 to %d characters", i+1, NameMaxLength)
+		}
+		if categories[category.Name] {
+			return fmt.Errorf("category %d: name %q is used twice", i+1, category.Name)
+		}
+		if category.Parent != "" && !categories[category.Parent] {
+			return fmt.Errorf("category %d: parent %q is not listed before it", i+1, category.Parent)
+		}
+		categories[category.Name] = true
+	}
+
+	for i := range f.Notes {
+		note := &f.Notes[i]
+		if length := utf8.RuneCountInString(note.Title); length < 2 || length > TitleMaxLength {
+			return fmt.Errorf("note %d: title must have 2 to %d characters", i+1, TitleMaxLength)
+		}
+		if utf8.RuneCountInString(note.Body) < 2 {
+			return fmt.Errorf("note %d: body must have at least 2 characters", i+1)
+		}
+		if note.Format == "" {
+			note.Format = web.NoteFormatPlain
+		}
+		if note.Format != web.NoteFormatPlain && note.Format != web.NoteFormatMarkdown {
+			return fmt.Errorf("note %d: format %q is invalid", i+1, note.Format)
+		}
+		if !categories[note.Category] {
+			return fmt.Errorf("note %d: category %q is not listed", i+1, note.Category)
+		}
+		tags, errTags := service.NormalizeTagNames(note.Tags)
+		if errTags != nil {
+			return fmt.Errorf("note %d: %w", i+1, errTags)
+		}
+		for _, tag := range tags {
+			if utf8.RuneCountInString(tag) > TagMaxLength {
+				return fmt.Errorf("note %d: tag %q is longer than %d characters", i+1, tag, TagMaxLength)
+			}
+		}
+		note.Tags = tags
+	}
+	return nil
+}
+
+// LoadFixture reads the fixture called name from fsys and inserts it for the
+// user in one transaction.
+func LoadFixture(db *gorm.DB, fsys fs.FS, name string, userID int) (Result, error) {
+	var result Result
+	fixture, errRead := ReadFixture(fsys, name)
+	if errRead != nil {
+		return result, errRead
+	}
+
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		categoryList := make([]domain.Category, len(fixture.Categories))
+		for i, category := range fixture.Categories {
+			categoryList[i] = domain.Category{Name: category.Name, UserID: userID, Version: 1}
+		}
+		if err := insert(tx, &categoryList); err != nil {
+			return err
+		}
+
+		categoryIDs := map[string]int{}
+		for i, category := range fixture.Categories {
+			categoryIDs[category.Name] = categoryList[i].ID
+			if category.Parent == "" {
+				continue
+			}
+			parentID := categoryIDs[category.Parent]
+			categoryList[i].ParentID = &parentID
+			if err := tx.Model(&domain.Category{}).Where("id = ?", categoryList[i].ID).
+				Update("parent_id", parentID).Error; err != nil {
+				return err
+			}
+		}
+
+		noteList := make([]domain.Note, len(fixture.Notes))
+		for i, note := range fixture.Notes {
+			noteList[i] = domain.Note{
+				Title:      note.Title,
+				Body:       note.Body,
+				Format:     note.Format,
+				CategoryID: categoryIDs[note.Category],
+				UserID:     userID,
+				Version:    1,
+			}
+		}
+		if err := insert(tx, &noteList); err != nil {
+			return err
+		}
+
+		var noteTags []domain.NoteTag
+		tagRepository := repository.NewTagRepository()
+		for i, note := range fixture.Notes {
+			tags, errTags := tagRepository.FindOrCreate(tx.Statement.Context, tx, userID, note.Tags)
+			if errTags != nil {
+				return errTags
+			}
+			for _, tag := range tags {
+				noteTags = append(noteTags, domain.NoteTag{NoteID: noteList[i].ID, TagID: tag.ID})
+			}
+		}
+		if err := insert(tx, &noteTags); err != nil {
+			return err
+		}
+
+		result = Result{Categories: categoryList, Notes: noteList}
+		return nil
+	})
+
+	return result, errTx
+}
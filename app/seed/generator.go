@@ -0,0 +1,135 @@
+package seed
+
+import (
+	"math/rand"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the generated and fixture data, the same the API validates.
+const (
+	NameMaxLength  = 100
+	TitleMaxLength = 100
+	TagMaxLength   = 50
+)
+
+var nouns = strings.Fields(`account agenda answer article budget bug calendar
+	campaign chapter checklist client code contract course customer dashboard
+	deadline design draft estimate event feature feedback garden goal grocery
+	habit holiday idea invoice issue journal kitchen launch lecture lesson list
+	meeting menu milestone module movie note office order outline package plan
+	playlist podcast policy portfolio presentation project proposal question
+	receipt recipe release report request research review roadmap routine
+	schedule script server session sketch sprint story strategy summary task
+	team template test ticket timeline topic training trip update vacation
+	workout workshop`)
+
+var adjectives = strings.Fields(`annual important weekly daily quick rough
+	final early late shared personal private open pending urgent simple new
+	old small big monthly quarterly remote local internal external next last
+	main short long`)
+
+var verbs = strings.Fields(`review update finish prepare write check plan
+	send share fix follow discuss organize schedule draft test clean book
+	order call compare collect track read move cancel confirm`)
+
+var fillers = strings.Fields(`the a this that our my every some each another
+	before after during with without about for from into over under around`)
+
+// Generator makes word based names, titles and bodies. Everything it returns
+// depends only on the seed, so the same seed produces the same data.
+type Generator struct {
+	random *rand.Rand
+}
+
+func NewGenerator(seed int64) *Generator {
+	return &Generator{random: rand.New(rand.NewSource(seed))}
+}
+
+// Intn is the source of the generator, for choices it doesn't make itself.
+func (g *Generator) Intn(n int) int {
+	return g.random.Intn(n)
+}
+
+func (g *Generator) between(min int, max int) int {
+	return min + g.random.Intn(max-min+1)
+}
+
+func (g *Generator) pick(words []string) string {
+	return words[g.random.Intn(len(words))]
+}
+
+// CategoryName returns one to three capitalized words, like "Weekly Budget".
+func (g *Generator) CategoryName() string {
+	words := []string{g.pick(nouns)}
+	switch g.random.Intn(3) {
+	case 1:
+		words = append([]string{g.pick(adjectives)}, words...)
+	case 2:
+		words = append([]string{g.pick(adjectives)}, append(words, g.pick(nouns))...)
+	}
+	for i, word := range words {
+		words[i] = capitalize(word)
+	}
+	return limit(strings.Join(words, " "), NameMaxLength)
+}
+
+// NoteTitle returns a short imperative sentence without a full stop.
+func (g *Generator) NoteTitle() string {
+	words := []string{g.pick(verbs), g.pick(fillers), g.pick(nouns)}
+	if g.random.Intn(2) == 0 {
+		words = append(words, g.pick(fillers), g.pick(adjectives), g.pick(nouns))
+	}
+	return limit(capitalize(strings.Join(words, " ")), TitleMaxLength)
+}
+
+// Sentence returns five to fourteen words ending with a full stop.
+func (g *Generator) Sentence() string {
+	n := g.between(5, 14)
+	words := make([]string, 0, n)
+	for len(words) < n {
+		switch g.random.Intn(4) {
+		case 0:
+			words = append(words, g.pick(verbs))
+		case 1:
+			words = append(words, g.pick(adjectives))
+		case 2:
+			words = append(words, g.pick(fillers))
+		default:
+			words = append(words, g.pick(nouns))
+		}
+	}
+	return capitalize(strings.Join(words, " ")) + "."
+}
+
+// NoteBody returns one to four paragraphs of two to six sentences.
+func (g *Generator) NoteBody() string {
+	paragraphs := make([]string, g.between(1, 4))
+	for i := range paragraphs {
+		sentences := make([]string, g.between(2, 6))
+		for j := range sentences {
+			sentences[j] = g.Sentence()
+		}
+		paragraphs[i] = strings.Join(sentences, " ")
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// limit cuts s to at most max characters at a word boundary.
+func limit(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	cut := string([]rune(s)[:max])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return cut
+}
@@ -0,0 +1,102 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BatchSize is the number of rows sent in one INSERT.
+const BatchSize = 500
+
+type Options struct {
+	Categories   int
+	Notes        int
+	Distribution string
+	// Seed makes the generated data repeatable.
+	Seed int64
+}
+
+type Result struct {
+	Categories []domain.Category
+	Notes      []domain.Note
+}
+
+// Run adds generated categories and notes for the user in one transaction.
+func Run(db *gorm.DB, userID int, options Options) (Result, error) {
+	var result Result
+	if options.Distribution == "" {
+		options.Distribution = DistributionUniform
+	}
+	if !IsDistribution(options.Distribution) {
+		return result, fmt.Errorf("distribution is one of %s", strings.Join(Distributions, ", "))
+	}
+	generator := NewGenerator(options.Seed)
+
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		categoryList, errCategories := Categories(tx, generator, userID, options.Categories)
+		if errCategories != nil {
+			return errCategories
+		}
+		noteList, errNotes := Notes(tx, generator, categoryList, options.Notes, options.Distribution)
+		if errNotes != nil {
+			return errNotes
+		}
+		result = Result{Categories: categoryList, Notes: noteList}
+		return nil
+	})
+
+	return result, errTx
+}
+
+// Categories inserts n generated categories for the user.
+func Categories(tx *gorm.DB, generator *Generator, userID int, n int) ([]domain.Category, error) {
+	categoryList := make([]domain.Category, n)
+	for i := range categoryList {
+		categoryList[i] = domain.Category{Name: generator.CategoryName(), UserID: userID, Version: 1}
+	}
+	if err := insert(tx, &categoryList); err != nil {
+		return nil, err
+	}
+
+	return categoryList, nil
+}
+
+// Notes inserts n generated notes spread over categoryList.
+func Notes(tx *gorm.DB, generator *Generator, categoryList []domain.Category, n int,
+	distribution string) ([]domain.Note, error) {
+	indexes, errAssign := assign(generator.random, distribution, n, len(categoryList))
+	if errAssign != nil {
+		return nil, errAssign
+	}
+
+	noteList := make([]domain.Note, len(indexes))
+	for i, index := range indexes {
+		category := categoryList[index]
+		noteList[i] = domain.Note{
+			Title:      generator.NoteTitle(),
+			Body:       generator.NoteBody(),
+			Format:     web.NoteFormatPlain,
+			CategoryID: category.ID,
+			UserID:     category.UserID,
+			Version:    1,
+		}
+	}
+	if err := insert(tx, &noteList); err != nil {
+		return nil, err
+	}
+
+	return noteList, nil
+}
+
+// insert creates the rows of a slice in batches and fills in their ids.
+func insert[T any](tx *gorm.DB, rows *[]T) error {
+	if len(*rows) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).CreateInBatches(rows, BatchSize).Error
+}
@@ -2,12 +2,24 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type AppConfig struct {
-	AppPort string
+	AppPort            string
+	CursorSecret       string
+	JWTSecret          string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+	NoteBodyMaxSize    int
+	RequestTimeout     time.Duration
+	ExportTimeout      time.Duration
+	ErrorFormat        string
 }
 
 func GetAppConfig(isUsingDotEnv bool) *AppConfig {
@@ -16,6 +28,39 @@ func GetAppConfig(isUsingDotEnv bool) *AppConfig {
 	}
 
 	return &AppConfig{
-		AppPort: os.Getenv("APP_PORT"),
+		AppPort:            os.Getenv("APP_PORT"),
+		CursorSecret:       os.Getenv("CURSOR_SECRET"),
+		JWTSecret:          os.Getenv("JWT_SECRET"),
+		AccessTokenTTL:     getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:    getDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		TrashRetention:     getDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: getDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		NoteBodyMaxSize:    getInt("NOTE_BODY_MAX_SIZE", 1<<20),
+		RequestTimeout:     getDuration("REQUEST_TIMEOUT", 30*time.Second),
+		ExportTimeout:      getDuration("EXPORT_TIMEOUT", 10*time.Minute),
+		ErrorFormat:        getString("ERROR_FORMAT", "default"),
 	}
 }
+
+func getString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+func getInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
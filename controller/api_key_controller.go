@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+type APIKeyController interface {
+	GetAll(c echo.Context) error
+	Create(c echo.Context) error
+	Rotate(c echo.Context) error
+	Revoke(c echo.Context) error
+}
+
+type apiKeyControllerImpl struct {
+	Service service.APIKeyService
+}
+
+func NewAPIKeyController(service service.APIKeyService) *apiKeyControllerImpl {
+	return &apiKeyControllerImpl{
+		Service: service,
+	}
+}
+
+func (ct *apiKeyControllerImpl) GetAll(c echo.Context) error {
+	apiKeyRes, errFind := ct.Service.GetAll(c.Request().Context(), helper.GetUserID(c))
+	if errFind != nil {
+		return errFind
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   apiKeyRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *apiKeyControllerImpl) Create(c echo.Context) error {
+	apiKeyReq := new(web.APIKeyRequest)
+	if errBind := c.Bind(apiKeyReq); errBind != nil {
+		return &exception.BadRequestError{Message: errBind.Error()}
+	}
+
+	apiKeyRes, errCreate := ct.Service.Create(c.Request().Context(), helper.GetUserID(c), *apiKeyReq)
+	if errCreate != nil {
+		return errCreate
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   apiKeyRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *apiKeyControllerImpl) Rotate(c echo.Context) error {
+	id := c.Param("id")
+	idInt, errConv := strconv.Atoi(id)
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "api key"}
+	}
+
+	apiKeyRes, errRotate := ct.Service.Rotate(c.Request().Context(), helper.GetUserID(c), idInt)
+	if errRotate != nil {
+		return errRotate
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   apiKeyRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *apiKeyControllerImpl) Revoke(c echo.Context) error {
+	id := c.Param("id")
+	idInt, errConv := strconv.Atoi(id)
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "api key"}
+	}
+
+	if errRevoke := ct.Service.Revoke(c.Request().Context(), helper.GetUserID(c), idInt); errRevoke != nil {
+		return errRevoke
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+	}
+	return c.JSON(http.StatusOK, response)
+}
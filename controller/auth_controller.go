@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+type AuthController interface {
+	Register(c echo.Context) error
+	Login(c echo.Context) error
+	Refresh(c echo.Context) error
+}
+
+type authControllerImpl struct {
+	Service service.AuthService
+}
+
+func NewAuthController(service service.AuthService) *authControllerImpl {
+	return &authControllerImpl{
+		Service: service,
+	}
+}
+
+func (ct *authControllerImpl) Register(c echo.Context) error {
+	registerReq := new(web.RegisterRequest)
+	if errBind := c.Bind(registerReq); errBind != nil {
+		return &exception.BadRequestError{Message: errBind.Error()}
+	}
+
+	userRes, errRegister := ct.Service.Register(c.Request().Context(), *registerReq)
+	if errRegister != nil {
+		return errRegister
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   userRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *authControllerImpl) Login(c echo.Context) error {
+	loginReq := new(web.LoginRequest)
+	if errBind := c.Bind(loginReq); errBind != nil {
+		return &exception.BadRequestError{Message: errBind.Error()}
+	}
+
+	tokenRes, errLogin := ct.Service.Login(c.Request().Context(), *loginReq)
+	if errLogin != nil {
+		return errLogin
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   tokenRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *authControllerImpl) Refresh(c echo.Context) error {
+	refreshReq := new(web.RefreshRequest)
+	if errBind := c.Bind(refreshReq); errBind != nil {
+		return &exception.BadRequestError{Message: errBind.Error()}
+	}
+
+	tokenRes, errRefresh := ct.Service.Refresh(c.Request().Context(), *refreshReq)
+	if errRefresh != nil {
+		return errRefresh
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   tokenRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
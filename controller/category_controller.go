@@ -2,10 +2,13 @@ package controller
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"github.com/naomigrain/echo-crud-notes/model/web"
 	"github.com/naomigrain/echo-crud-notes/service"
 )
@@ -16,33 +19,36 @@ type CategoryController interface {
 	GetAll(c echo.Context) error
 	Update(c echo.Context) error
 	Delete(c echo.Context) error
+	GetTree(c echo.Context) error
+	GetDescendants(c echo.Context) error
 }
 
 type categoryControllerImpl struct {
 	Service service.CategoryService
+	Cursor  *helper.CursorCodec
 }
 
-func NewCategoryController(service service.CategoryService) *categoryControllerImpl {
+func NewCategoryController(service service.CategoryService, cursor *helper.CursorCodec) *categoryControllerImpl {
 	return &categoryControllerImpl{
 		Service: service,
+		Cursor:  cursor,
 	}
 }
 
 func (ct *categoryControllerImpl) GetAll(c echo.Context) error {
-	page := c.QueryParam("page")
-	pageSize := c.QueryParam("pageSize")
-	pageInt, _ := strconv.Atoi(page)
-	pageSizeInt, _ := strconv.Atoi(pageSize)
+	spec, errSpec := newQuerySpec(c, categoryFilterParams, ct.Cursor)
+	if errSpec != nil {
+		return errSpec
+	}
 
-	categories, errFind := ct.Service.GetAll(pageInt, pageSizeInt)
+	categories, info, errFind := ct.Service.GetAll(c.Request().Context(), helper.GetUserID(c), spec)
 	if errFind != nil {
 		return errFind
 	}
 
-	res := web.WebResponse{
-		Code:   http.StatusOK,
-		Status: "OK",
-		Data:   categories,
+	res, errResponse := newListResponse(c, categories, spec, info, ct.Cursor)
+	if errResponse != nil {
+		return errResponse
 	}
 	return c.JSON(http.StatusOK, res)
 }
@@ -54,17 +60,20 @@ func (ct *categoryControllerImpl) GetById(c echo.Context) error {
 		return &exception.NotFoundError{Entity: "category"}
 	}
 
-	categoryRes, errFind := ct.Service.GetById(idInt)
+	categoryRes, errFind := ct.Service.GetById(c.Request().Context(), helper.GetUserID(c), idInt)
 	if errFind != nil {
 		return errFind
 	}
+	if isNotModified, errRes := notModified(c, categoryRes.Version); isNotModified {
+		return errRes
+	}
 
 	res := web.WebResponse{
 		Code:   http.StatusOK,
 		Status: "OK",
 		Data:   categoryRes,
 	}
-	return c.JSON(http.StatusInternalServerError, res)
+	return c.JSON(http.StatusOK, res)
 }
 
 func (ct *categoryControllerImpl) Create(c echo.Context) error {
@@ -73,10 +82,11 @@ func (ct *categoryControllerImpl) Create(c echo.Context) error {
 		return &exception.BadRequestError{Message: errBind.Error()}
 	}
 
-	categoryRes, errCreate := ct.Service.Create(*categoryReq)
+	categoryRes, errCreate := ct.Service.Create(c.Request().Context(), helper.GetUserID(c), *categoryReq)
 	if errCreate != nil {
 		return errCreate
 	}
+	setETag(c, categoryRes.Version)
 
 	res := web.WebResponse{
 		Code:   http.StatusOK,
@@ -93,16 +103,23 @@ func (ct *categoryControllerImpl) Update(c echo.Context) error {
 		return &exception.NotFoundError{Entity: "category"}
 	}
 
+	versions, errVersion := ifMatchVersions(c)
+	if errVersion != nil {
+		return errVersion
+	}
+
 	categoryReq := new(web.CategoryJSON)
 	if errBind := c.Bind(categoryReq); errBind != nil {
 		return &exception.BadRequestError{Message: errBind.Error()}
 	}
 
 	categoryReq.ID = idInt
-	categoryRes, errUpdate := ct.Service.Update(*categoryReq)
+	categoryReq.Versions = versions
+	categoryRes, errUpdate := ct.Service.Update(c.Request().Context(), helper.GetUserID(c), *categoryReq)
 	if errUpdate != nil {
 		return errUpdate
 	}
+	setETag(c, categoryRes.Version)
 
 	res := web.WebResponse{
 		Code:   http.StatusOK,
@@ -119,7 +136,39 @@ func (ct *categoryControllerImpl) Delete(c echo.Context) error {
 		return &exception.NotFoundError{Entity: "category"}
 	}
 
-	errDel := ct.Service.Delete(idInt)
+	versions, errVersion := ifMatchVersions(c)
+	if errVersion != nil {
+		return errVersion
+	}
+
+	deleteReq := web.CategoryDeleteRequest{
+		ID:       idInt,
+		Versions: versions,
+		Strategy: c.QueryParam("strategy"),
+	}
+	if target := c.QueryParam("target"); target != "" {
+		targetInt, errTarget := strconv.Atoi(target)
+		if errTarget != nil {
+			return &exception.BadRequestError{Message: "target is invalid"}
+		}
+		deleteReq.TargetID = targetInt
+	}
+	// cascade and reassign change notes too, so an api key needs to be allowed
+	// to write them.
+	if deleteReq.Strategy == web.CategoryDeleteCascade || deleteReq.Strategy == web.CategoryDeleteReassign {
+		if scopes, isAPIKey := helper.GetScopes(c); isAPIKey && !slices.Contains(scopes, domain.ScopeNotesWrite) {
+			return &exception.ForbiddenError{Message: "api key is missing scope " + domain.ScopeNotesWrite}
+		}
+	}
+
+	var deleteRes web.CategoryDeleteResponse
+	var errDel error
+	// Admins may delete the categories of every user.
+	if helper.GetRole(c) == domain.RoleAdmin {
+		deleteRes, errDel = ct.Service.DeleteAny(c.Request().Context(), deleteReq)
+	} else {
+		deleteRes, errDel = ct.Service.Delete(c.Request().Context(), helper.GetUserID(c), deleteReq)
+	}
 	if errDel != nil {
 		return errDel
 	}
@@ -127,6 +176,41 @@ func (ct *categoryControllerImpl) Delete(c echo.Context) error {
 	res := web.WebResponse{
 		Code:   http.StatusOK,
 		Status: "OK",
+		Data:   deleteRes,
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (ct *categoryControllerImpl) GetTree(c echo.Context) error {
+	tree, errFind := ct.Service.GetTree(c.Request().Context(), helper.GetUserID(c))
+	if errFind != nil {
+		return errFind
+	}
+
+	res := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   tree,
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (ct *categoryControllerImpl) GetDescendants(c echo.Context) error {
+	id := c.Param("id")
+	idInt, errConv := strconv.Atoi(id)
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "category"}
+	}
+
+	categories, errFind := ct.Service.GetDescendants(c.Request().Context(), helper.GetUserID(c), idInt)
+	if errFind != nil {
+		return errFind
+	}
+
+	res := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   categories,
 	}
 	return c.JSON(http.StatusOK, res)
 }
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+)
+
+func setETag(c echo.Context, version int) {
+	c.Response().Header().Set(helper.HeaderETag, helper.ETag(version))
+}
+
+// notModified answers a conditional GET with 304 when the client already has
+// the current version.
+func notModified(c echo.Context, version int) (bool, error) {
+	setETag(c, version)
+	if header := c.Request().Header.Get(helper.HeaderIfNoneMatch); header != "" && helper.MatchIfNoneMatch(header, version) {
+		return true, c.NoContent(http.StatusNotModified)
+	}
+	return false, nil
+}
+
+// ifMatchVersions reads the versions a PUT or DELETE may be based on. It
+// returns nil when the client sent "*" and is fine with overwriting any
+// version.
+func ifMatchVersions(c echo.Context) ([]int, error) {
+	header := c.Request().Header.Get(helper.HeaderIfMatch)
+	if header == "" {
+		return nil, &exception.PreconditionRequiredError{Message: "If-Match header is required"}
+	}
+
+	versions, errParse := helper.ParseIfMatch(header)
+	if errParse != nil {
+		return nil, &exception.BadRequestError{Message: "If-Match header is invalid"}
+	}
+	return versions, nil
+}
@@ -3,9 +3,11 @@ package controller
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
 	"github.com/naomigrain/echo-crud-notes/model/web"
 	"github.com/naomigrain/echo-crud-notes/service"
 )
@@ -16,33 +18,58 @@ type NoteController interface {
 	Create(c echo.Context) error
 	Update(c echo.Context) error
 	Delete(c echo.Context) error
+	GetTrash(c echo.Context) error
+	Restore(c echo.Context) error
+	Purge(c echo.Context) error
+	Batch(c echo.Context) error
 }
 
 type noteControllerImpl struct {
 	Service service.NoteService
+	Cursor  *helper.CursorCodec
 }
 
-func NewNoteController(service service.NoteService) *noteControllerImpl {
+func NewNoteController(service service.NoteService, cursor *helper.CursorCodec) *noteControllerImpl {
 	return &noteControllerImpl{
 		Service: service,
+		Cursor:  cursor,
 	}
 }
 
 func (ct *noteControllerImpl) GetAll(c echo.Context) error {
-	page := c.QueryParam("page")
-	pageSize := c.QueryParam("pageSize")
-	pageInt, _ := strconv.Atoi(page)
-	pageSizeInt, _ := strconv.Atoi(pageSize)
+	spec, errSpec := newQuerySpec(c, noteFilterParams, ct.Cursor)
+	if errSpec != nil {
+		return errSpec
+	}
+	tags, errTags := newTagFilter(c)
+	if errTags != nil {
+		return errTags
+	}
+	spec.Tags = tags
+	categoryTree, errTree := newCategoryTree(c)
+	if errTree != nil {
+		return errTree
+	}
+	spec.CategoryTree = categoryTree
 
-	noteRes, errFind := ct.Service.GetAll(pageInt, pageSizeInt)
+	var noteRes []web.NoteResponse
+	var info helper.PageInfo
+	var errFind error
+	if query := strings.TrimSpace(c.QueryParam("q")); query != "" {
+		if spec.Keyset {
+			return &exception.BadRequestError{Message: "cursor can not be combined with q"}
+		}
+		noteRes, info, errFind = ct.Service.Search(c.Request().Context(), helper.GetUserID(c), query, spec)
+	} else {
+		noteRes, info, errFind = ct.Service.GetAll(c.Request().Context(), helper.GetUserID(c), spec)
+	}
 	if errFind != nil {
 		return errFind
 	}
 
-	response := web.WebResponse{
-		Code:   http.StatusOK,
-		Status: "OK",
-		Data:   noteRes,
+	response, errResponse := newListResponse(c, noteRes, spec, info, ct.Cursor)
+	if errResponse != nil {
+		return errResponse
 	}
 	return c.JSON(http.StatusOK, response)
 }
@@ -53,10 +80,17 @@ func (ct *noteControllerImpl) GetById(c echo.Context) error {
 	if errConv != nil {
 		return &exception.NotFoundError{Entity: "note"}
 	}
+	render := c.QueryParam("render")
+	if render != "" && render != web.NoteRenderHTML {
+		return &exception.BadRequestError{Message: "render is invalid"}
+	}
 
-	noteRes, errFind := ct.Service.GetById(idInt)
+	noteRes, errFind := ct.Service.GetById(c.Request().Context(), helper.GetUserID(c), idInt, render)
 	if errFind != nil {
-		return &exception.NotFoundError{Entity: "note"}
+		return errFind
+	}
+	if isNotModified, errRes := notModified(c, noteRes.Version); isNotModified {
+		return errRes
 	}
 
 	response := web.WebResponse{
@@ -73,10 +107,11 @@ func (ct *noteControllerImpl) Create(c echo.Context) error {
 		return &exception.BadRequestError{Message: errBind.Error()}
 	}
 
-	noteRes, errCreate := ct.Service.Create(*noteReq)
+	noteRes, errCreate := ct.Service.Create(c.Request().Context(), helper.GetUserID(c), *noteReq)
 	if errCreate != nil {
 		return errCreate
 	}
+	setETag(c, noteRes.Version)
 
 	response := web.WebResponse{
 		Code:   http.StatusOK,
@@ -92,6 +127,10 @@ func (ct *noteControllerImpl) Update(c echo.Context) error {
 	if errConv != nil {
 		return errConv
 	}
+	versions, errVersion := ifMatchVersions(c)
+	if errVersion != nil {
+		return errVersion
+	}
 
 	noteReq := new(web.NoteRequest)
 	if errBind := c.Bind(noteReq); errBind != nil {
@@ -99,10 +138,12 @@ func (ct *noteControllerImpl) Update(c echo.Context) error {
 	}
 
 	noteReq.ID = idInt
-	noteRes, errUpdate := ct.Service.Update(*noteReq)
+	noteReq.Versions = versions
+	noteRes, errUpdate := ct.Service.Update(c.Request().Context(), helper.GetUserID(c), *noteReq)
 	if errUpdate != nil {
 		return errUpdate
 	}
+	setETag(c, noteRes.Version)
 
 	response := web.WebResponse{
 		Code:   http.StatusOK,
@@ -119,7 +160,12 @@ func (ct *noteControllerImpl) Delete(c echo.Context) error {
 		return &exception.NotFoundError{Entity: "note"}
 	}
 
-	errDel := ct.Service.Delete(idInt)
+	versions, errVersion := ifMatchVersions(c)
+	if errVersion != nil {
+		return errVersion
+	}
+
+	errDel := ct.Service.Delete(c.Request().Context(), helper.GetUserID(c), idInt, versions)
 	if errDel != nil {
 		return errDel
 	}
@@ -130,3 +176,94 @@ func (ct *noteControllerImpl) Delete(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, response)
 }
+
+func (ct *noteControllerImpl) GetTrash(c echo.Context) error {
+	spec, errSpec := newQuerySpec(c, noteFilterParams, ct.Cursor)
+	if errSpec != nil {
+		return errSpec
+	}
+	tags, errTags := newTagFilter(c)
+	if errTags != nil {
+		return errTags
+	}
+	spec.Tags = tags
+	categoryTree, errTree := newCategoryTree(c)
+	if errTree != nil {
+		return errTree
+	}
+	spec.CategoryTree = categoryTree
+
+	noteRes, info, errFind := ct.Service.GetTrash(c.Request().Context(), helper.GetUserID(c), spec)
+	if errFind != nil {
+		return errFind
+	}
+
+	response, errResponse := newListResponse(c, noteRes, spec, info, ct.Cursor)
+	if errResponse != nil {
+		return errResponse
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *noteControllerImpl) Restore(c echo.Context) error {
+	id := c.Param("id")
+	idInt, errConv := strconv.Atoi(id)
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "note"}
+	}
+
+	noteRes, errRestore := ct.Service.Restore(c.Request().Context(), helper.GetUserID(c), idInt)
+	if errRestore != nil {
+		return errRestore
+	}
+	setETag(c, noteRes.Version)
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   noteRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *noteControllerImpl) Purge(c echo.Context) error {
+	id := c.Param("id")
+	idInt, errConv := strconv.Atoi(id)
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "note"}
+	}
+
+	if errPurge := ct.Service.Purge(c.Request().Context(), helper.GetUserID(c), idInt); errPurge != nil {
+		return errPurge
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *noteControllerImpl) Batch(c echo.Context) error {
+	batchReq := new(web.NoteBatchRequest)
+	if errBind := c.Bind(batchReq); errBind != nil {
+		return &exception.BadRequestError{Message: errBind.Error()}
+	}
+
+	batchRes, errBatch := ct.Service.Batch(c.Request().Context(), helper.GetUserID(c), *batchReq)
+	if errBatch != nil {
+		return errBatch
+	}
+
+	// Nothing was written when an atomic batch failed.
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   batchRes,
+	}
+	if !batchRes.Committed {
+		response.Code = http.StatusUnprocessableEntity
+		response.Status = "UNPROCESSABLE ENTITY"
+	}
+	return c.JSON(response.Code, response)
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+type NoteRevisionController interface {
+	GetAll(c echo.Context) error
+	GetByRevision(c echo.Context) error
+	Diff(c echo.Context) error
+	Restore(c echo.Context) error
+}
+
+type noteRevisionControllerImpl struct {
+	Service service.NoteRevisionService
+}
+
+func NewNoteRevisionController(service service.NoteRevisionService) *noteRevisionControllerImpl {
+	return &noteRevisionControllerImpl{
+		Service: service,
+	}
+}
+
+func revisionParams(c echo.Context) (int, int, error) {
+	noteID, errConv := strconv.Atoi(c.Param("id"))
+	if errConv != nil {
+		return 0, 0, &exception.NotFoundError{Entity: "note"}
+	}
+	revision, errConv := strconv.Atoi(c.Param("rev"))
+	if errConv != nil {
+		return 0, 0, &exception.NotFoundError{Entity: "revision"}
+	}
+
+	return noteID, revision, nil
+}
+
+func (ct *noteRevisionControllerImpl) GetAll(c echo.Context) error {
+	noteID, errConv := strconv.Atoi(c.Param("id"))
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "note"}
+	}
+
+	revisionRes, errFind := ct.Service.GetAll(c.Request().Context(), helper.GetUserID(c), noteID)
+	if errFind != nil {
+		return errFind
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   revisionRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *noteRevisionControllerImpl) GetByRevision(c echo.Context) error {
+	noteID, revision, errParam := revisionParams(c)
+	if errParam != nil {
+		return errParam
+	}
+
+	revisionRes, errFind := ct.Service.GetByRevision(c.Request().Context(), helper.GetUserID(c), noteID, revision)
+	if errFind != nil {
+		return errFind
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   revisionRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *noteRevisionControllerImpl) Diff(c echo.Context) error {
+	noteID, errConv := strconv.Atoi(c.Param("id"))
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "note"}
+	}
+	from, errFrom := strconv.Atoi(c.QueryParam("from"))
+	to, errTo := strconv.Atoi(c.QueryParam("to"))
+	if errFrom != nil || errTo != nil {
+		return &exception.BadRequestError{Message: "from and to must be revision numbers"}
+	}
+
+	diffRes, errDiff := ct.Service.Diff(c.Request().Context(), helper.GetUserID(c), noteID, from, to)
+	if errDiff != nil {
+		return errDiff
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   diffRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *noteRevisionControllerImpl) Restore(c echo.Context) error {
+	noteID, revision, errParam := revisionParams(c)
+	if errParam != nil {
+		return errParam
+	}
+
+	noteRes, errRestore := ct.Service.Restore(c.Request().Context(), helper.GetUserID(c), noteID, revision)
+	if errRestore != nil {
+		return errRestore
+	}
+	setETag(c, noteRes.Version)
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   noteRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+type filterParam struct {
+	Field    string
+	Operator string
+	Parse    func(value string) (interface{}, error)
+}
+
+var noteFilterParams = map[string]filterParam{
+	"category_id":    {Field: "category_id", Operator: helper.FilterEqual, Parse: parseIntParam},
+	"title_contains": {Field: "title", Operator: helper.FilterContains, Parse: parseStringParam},
+	"created_after":  {Field: "created_at", Operator: helper.FilterAfter, Parse: parseTimeParam},
+	"created_before": {Field: "created_at", Operator: helper.FilterBefore, Parse: parseTimeParam},
+}
+
+var categoryFilterParams = map[string]filterParam{
+	"name_contains":  {Field: "name", Operator: helper.FilterContains, Parse: parseStringParam},
+	"created_after":  {Field: "created_at", Operator: helper.FilterAfter, Parse: parseTimeParam},
+	"created_before": {Field: "created_at", Operator: helper.FilterBefore, Parse: parseTimeParam},
+}
+
+func newQuerySpec(c echo.Context, filterParams map[string]filterParam, codec *helper.CursorCodec) (helper.QuerySpec, error) {
+	var spec helper.QuerySpec
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	pageSize, _ := strconv.Atoi(c.QueryParam("pageSize"))
+	spec.Page, spec.PageSize = helper.NormalizePage(page, pageSize)
+	spec.Sorts = helper.ParseSort(c.QueryParam("sort"))
+
+	if c.QueryParams().Has("cursor") {
+		spec.Keyset = true
+		if token := c.QueryParam("cursor"); token != "" {
+			cursor, errDecode := codec.Decode(token)
+			if errDecode != nil {
+				return spec, &exception.BadRequestError{Message: errDecode.Error()}
+			}
+			spec.After = &cursor
+		}
+	}
+
+	for name, param := range filterParams {
+		raw := c.QueryParam(name)
+		if raw == "" {
+			continue
+		}
+		value, errParse := param.Parse(raw)
+		if errParse != nil {
+			return spec, &exception.BadRequestError{Message: name + " is invalid"}
+		}
+		spec.Filters = append(spec.Filters, helper.FilterSpec{
+			Field:    param.Field,
+			Operator: param.Operator,
+			Value:    value,
+		})
+	}
+
+	return spec, nil
+}
+
+// newTagFilter reads repeated tag parameters such as ?tag=a&tag=b. Notes with
+// any of the tags match, or only notes with all of them when tag_match=all.
+func newTagFilter(c echo.Context) (helper.TagFilter, error) {
+	var filter helper.TagFilter
+	names, errNormalize := service.NormalizeTagNames(c.QueryParams()["tag"])
+	if errNormalize != nil {
+		return filter, errNormalize
+	}
+	filter.Names = names
+
+	switch c.QueryParam("tag_match") {
+	case "", "any":
+	case "all":
+		filter.All = true
+	default:
+		return filter, &exception.BadRequestError{Message: "tag_match is invalid"}
+	}
+
+	return filter, nil
+}
+
+// newCategoryTree reads under_category, which lists the notes of a category
+// together with the notes of all categories below it.
+func newCategoryTree(c echo.Context) (int, error) {
+	raw := c.QueryParam("under_category")
+	if raw == "" {
+		return 0, nil
+	}
+
+	categoryID, errConv := strconv.Atoi(raw)
+	if errConv != nil || categoryID < 1 {
+		return 0, &exception.BadRequestError{Message: "under_category is invalid"}
+	}
+	return categoryID, nil
+}
+
+func newListResponse(c echo.Context, data interface{}, spec helper.QuerySpec, info helper.PageInfo,
+	codec *helper.CursorCodec) (web.WebResponse, error) {
+	res := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   data,
+	}
+
+	if spec.Keyset {
+		meta := &web.CursorMeta{PageSize: spec.PageSize}
+		if info.Next != nil {
+			token, errEncode := codec.Encode(*info.Next)
+			if errEncode != nil {
+				return res, errEncode
+			}
+			meta.NextCursor = token
+		}
+		res.Meta = meta
+		res.Links = helper.NewCursorLinks(requestUrl(c), meta)
+		return res, nil
+	}
+
+	meta := helper.NewPageMeta(spec.Page, spec.PageSize, info.TotalItems)
+	res.Meta = meta
+	res.Links = helper.NewPageLinks(requestUrl(c), meta)
+	return res, nil
+}
+
+func requestUrl(c echo.Context) *url.URL {
+	u := *c.Request().URL
+	u.Scheme = c.Scheme()
+	u.Host = c.Request().Host
+	return &u
+}
+
+func parseStringParam(value string) (interface{}, error) {
+	return value, nil
+}
+
+func parseIntParam(value string) (interface{}, error) {
+	return strconv.Atoi(value)
+}
+
+func parseTimeParam(value string) (interface{}, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+type TagController interface {
+	GetAll(c echo.Context) error
+	GetById(c echo.Context) error
+	Create(c echo.Context) error
+	Update(c echo.Context) error
+	Delete(c echo.Context) error
+}
+
+type tagControllerImpl struct {
+	Service service.TagService
+}
+
+func NewTagController(service service.TagService) *tagControllerImpl {
+	return &tagControllerImpl{
+		Service: service,
+	}
+}
+
+func (ct *tagControllerImpl) GetAll(c echo.Context) error {
+	tagRes, errFind := ct.Service.GetAll(c.Request().Context(), helper.GetUserID(c))
+	if errFind != nil {
+		return errFind
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   tagRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *tagControllerImpl) GetById(c echo.Context) error {
+	id := c.Param("id")
+	idInt, errConv := strconv.Atoi(id)
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "tag"}
+	}
+
+	tagRes, errFind := ct.Service.GetById(c.Request().Context(), helper.GetUserID(c), idInt)
+	if errFind != nil {
+		return errFind
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   tagRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *tagControllerImpl) Create(c echo.Context) error {
+	tagReq := new(web.TagRequest)
+	if errBind := c.Bind(tagReq); errBind != nil {
+		return &exception.BadRequestError{Message: errBind.Error()}
+	}
+
+	tagRes, errCreate := ct.Service.Create(c.Request().Context(), helper.GetUserID(c), *tagReq)
+	if errCreate != nil {
+		return errCreate
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   tagRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *tagControllerImpl) Update(c echo.Context) error {
+	id := c.Param("id")
+	idInt, errConv := strconv.Atoi(id)
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "tag"}
+	}
+
+	tagReq := new(web.TagRequest)
+	if errBind := c.Bind(tagReq); errBind != nil {
+		return &exception.BadRequestError{Message: errBind.Error()}
+	}
+
+	tagReq.ID = idInt
+	tagRes, errUpdate := ct.Service.Update(c.Request().Context(), helper.GetUserID(c), *tagReq)
+	if errUpdate != nil {
+		return errUpdate
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   tagRes,
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (ct *tagControllerImpl) Delete(c echo.Context) error {
+	id := c.Param("id")
+	idInt, errConv := strconv.Atoi(id)
+	if errConv != nil {
+		return &exception.NotFoundError{Entity: "tag"}
+	}
+
+	if errDel := ct.Service.Delete(c.Request().Context(), helper.GetUserID(c), idInt); errDel != nil {
+		return errDel
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/service"
+)
+
+var exportFiles = map[string]struct {
+	ContentType string
+	Extension   string
+}{
+	web.FormatJSONL:    {ContentType: "application/x-ndjson", Extension: "jsonl"},
+	web.FormatCSV:      {ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+	web.FormatMarkdown: {ContentType: "application/zip", Extension: "zip"},
+}
+
+type TransferController interface {
+	Export(c echo.Context) error
+	Import(c echo.Context) error
+}
+
+type transferControllerImpl struct {
+	Service service.TransferService
+}
+
+func NewTransferController(service service.TransferService) *transferControllerImpl {
+	return &transferControllerImpl{
+		Service: service,
+	}
+}
+
+func transferFormat(c echo.Context) (string, error) {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = web.FormatJSONL
+	}
+	if !service.IsTransferFormat(format) {
+		return format, &exception.BadRequestError{Message: "format is invalid"}
+	}
+	return format, nil
+}
+
+// Export streams the file as it is written, so the status can't change once
+// the first record went out.
+func (ct *transferControllerImpl) Export(c echo.Context) error {
+	format, errFormat := transferFormat(c)
+	if errFormat != nil {
+		return errFormat
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, exportFiles[format].ContentType)
+	res.Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="notes.%s"`, exportFiles[format].Extension))
+	res.WriteHeader(http.StatusOK)
+
+	return ct.Service.Export(c.Request().Context(), helper.GetUserID(c), format, res)
+}
+
+// Import takes the file either as the request body or as the file field of a
+// multipart form.
+func (ct *transferControllerImpl) Import(c echo.Context) error {
+	format, errFormat := transferFormat(c)
+	if errFormat != nil {
+		return errFormat
+	}
+	importReq := web.ImportRequest{Format: format, IDs: c.QueryParam("ids")}
+	if dryRun := c.QueryParam("dry_run"); dryRun != "" {
+		isDryRun, errParse := strconv.ParseBool(dryRun)
+		if errParse != nil {
+			return &exception.BadRequestError{Message: "dry_run is invalid"}
+		}
+		importReq.DryRun = isDryRun
+	}
+
+	body := c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fileHeader, errForm := c.FormFile("file")
+		if errForm != nil {
+			return &exception.BadRequestError{Message: "file is required"}
+		}
+		file, errOpen := fileHeader.Open()
+		if errOpen != nil {
+			return errOpen
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, errRead := io.ReadAll(io.LimitReader(body, service.ImportMaxSize+1))
+	if errRead != nil {
+		return &exception.BadRequestError{Message: errRead.Error()}
+	}
+	if len(data) > service.ImportMaxSize {
+		return &exception.BadRequestError{Message: fmt.Sprintf("import can be at most %d MB", service.ImportMaxSize>>20)}
+	}
+	importReq.Data = data
+
+	report, errImport := ct.Service.Import(c.Request().Context(), helper.GetUserID(c), importReq)
+	if errImport != nil {
+		return errImport
+	}
+
+	response := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   report,
+	}
+	return c.JSON(http.StatusOK, response)
+}
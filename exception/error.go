@@ -2,12 +2,19 @@ package exception
 
 //
 
+// NotFoundError is returned when a record does not exist. Err, when set, is
+// the error of the database behind it.
 type NotFoundError struct {
 	Entity string
+	Err    error
 }
 
 func (e *NotFoundError) Error() string {
-	return e.Entity + " not found"
+	return withCause(e.Entity+" not found", e.Err)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
 }
 
 //
@@ -19,3 +26,118 @@ type BadRequestError struct {
 func (e *BadRequestError) Error() string {
 	return e.Message
 }
+
+//
+
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Message
+}
+
+//
+
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+//
+
+// ConflictError is returned when a change clashes with the current state of
+// other records. Details is sent back to the client as is, Err is only logged.
+type ConflictError struct {
+	Message string
+	Details interface{}
+	Err     error
+}
+
+func (e *ConflictError) Error() string {
+	return withCause(e.Message, e.Err)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+//
+
+// ReferenceError is returned when a record points to one that does not exist.
+// Err is only logged.
+type ReferenceError struct {
+	Message string
+	Err     error
+}
+
+func (e *ReferenceError) Error() string {
+	return withCause(e.Message, e.Err)
+}
+
+func (e *ReferenceError) Unwrap() error {
+	return e.Err
+}
+
+//
+
+// CheckError is returned when a record breaks a check constraint of the
+// database. Err is only logged.
+type CheckError struct {
+	Message string
+	Err     error
+}
+
+func (e *CheckError) Error() string {
+	return withCause(e.Message, e.Err)
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+//
+
+// UnprocessableEntityError is returned when a request is well formed but the
+// records it points to can't be processed.
+type UnprocessableEntityError struct {
+	Message string
+}
+
+func (e *UnprocessableEntityError) Error() string {
+	return e.Message
+}
+
+//
+
+type PreconditionFailedError struct {
+	Message string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Message
+}
+
+//
+
+type PreconditionRequiredError struct {
+	Message string
+}
+
+func (e *PreconditionRequiredError) Error() string {
+	return e.Message
+}
+
+//
+
+// withCause appends the error behind message, which ends up in the logs but
+// never in responses.
+func withCause(message string, err error) string {
+	if err == nil {
+		return message
+	}
+	return message + ": " + err.Error()
+}
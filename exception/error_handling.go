@@ -1,22 +1,86 @@
 package exception
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/helper"
 	"github.com/naomigrain/echo-crud-notes/model/web"
 )
 
+// StatusClientClosedRequest is the non standard status nginx logs when the
+// client went away before the response was sent.
+const StatusClientClosedRequest = 499
+
+// stackTracer is implemented by errors that carry the stack they were raised
+// with, like service.PanicError.
+type stackTracer interface {
+	error
+	StackTrace() []byte
+}
+
+// internalErrorMessage is sent in place of the text of errors that aren't known.
+const internalErrorMessage = "internal server error"
+
+// CustomErrorHandler sends errors in the default format.
 func CustomErrorHandler(err error, c echo.Context) {
+	handleError(err, c, ErrorFormatDefault)
+}
+
+// NewErrorHandler sends errors in format, one of the ErrorFormat values.
+// Clients that accept application/problem+json get problem documents in
+// either format.
+func NewErrorHandler(format string) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		handleError(err, c, format)
+	}
+}
+
+func handleError(err error, c echo.Context, format string) {
+	res := NewTranslatedErrorResponse(err, helper.Translator(c.Request().Header.Get("Accept-Language")))
+
+	var errStack stackTracer
+	if errors.As(err, &errStack) {
+		c.Logger().Errorf("%v\n%s", err, errStack.StackTrace())
+	} else {
+		c.Logger().Error(err)
+	}
+	// A streamed response can fail after its status went out. Aborting the
+	// connection leaves the body without its end, so the client can tell it
+	// was cut off instead of taking it for complete.
+	if c.Response().Committed {
+		panic(http.ErrAbortHandler)
+	}
+	if format == ErrorFormatProblem || acceptsProblem(c.Request()) {
+		c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+		c.JSON(res.Code, NewProblemDetails(res, c))
+		return
+	}
+	c.JSON(res.Code, res)
+}
+
+// NewErrorResponse maps an error to the status and message sent to clients.
+// Errors that aren't known are reported as 500 without their text, which may
+// hold SQL or other internals; handleError logs them in full.
+func NewErrorResponse(err error) web.ErrorResponse {
+	return NewTranslatedErrorResponse(err, helper.Translator(""))
+}
+
+// NewTranslatedErrorResponse is NewErrorResponse with validation messages in
+// the locale of trans.
+func NewTranslatedErrorResponse(err error, trans ut.Translator) web.ErrorResponse {
 	var res web.ErrorResponse
 
-	if _, ok := err.(*NotFoundError); ok {
+	if castedErr, ok := err.(*NotFoundError); ok {
 		res.Code = http.StatusNotFound
 		res.Status = "NOT FOUND"
-		res.Message = err.Error()
+		res.Message = castedErr.Entity + " not found"
 	} else if errors.Is(err, echo.ErrNotFound) {
 		res.Code = http.StatusNotFound
 		res.Status = "NOT FOUND"
@@ -25,27 +89,76 @@ func CustomErrorHandler(err error, c echo.Context) {
 		res.Code = http.StatusBadRequest
 		res.Status = "BAD REQUEST"
 		res.Message = err.Error()
+	} else if _, ok := err.(*UnauthorizedError); ok {
+		res.Code = http.StatusUnauthorized
+		res.Status = "UNAUTHORIZED"
+		res.Message = err.Error()
+	} else if _, ok := err.(*ForbiddenError); ok {
+		res.Code = http.StatusForbidden
+		res.Status = "FORBIDDEN"
+		res.Message = err.Error()
+	} else if castedErr, ok := err.(*ConflictError); ok {
+		res.Code = http.StatusConflict
+		res.Status = "CONFLICT"
+		res.Message = castedErr.Message
+		res.Details = castedErr.Details
+	} else if castedErr, ok := err.(*ReferenceError); ok {
+		res.Code = http.StatusUnprocessableEntity
+		res.Status = "UNPROCESSABLE ENTITY"
+		res.Message = castedErr.Message
+	} else if _, ok := err.(*UnprocessableEntityError); ok {
+		res.Code = http.StatusUnprocessableEntity
+		res.Status = "UNPROCESSABLE ENTITY"
+		res.Message = err.Error()
+	} else if castedErr, ok := err.(*CheckError); ok {
+		res.Code = http.StatusUnprocessableEntity
+		res.Status = "UNPROCESSABLE ENTITY"
+		res.Message = castedErr.Message
+	} else if _, ok := err.(*PreconditionFailedError); ok {
+		res.Code = http.StatusPreconditionFailed
+		res.Status = "PRECONDITION FAILED"
+		res.Message = err.Error()
+	} else if _, ok := err.(*PreconditionRequiredError); ok {
+		res.Code = http.StatusPreconditionRequired
+		res.Status = "PRECONDITION REQUIRED"
+		res.Message = err.Error()
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		res.Code = http.StatusGatewayTimeout
+		res.Status = "GATEWAY TIMEOUT"
+		res.Message = "request took too long and was cancelled"
+	} else if errors.Is(err, context.Canceled) {
+		res.Code = StatusClientClosedRequest
+		res.Status = "CLIENT CLOSED REQUEST"
+		res.Message = "request was cancelled by the client"
 	} else if castedErr, ok := err.(validator.ValidationErrors); ok {
-		res.Code = http.StatusBadRequest
-		res.Status = "BAD REQUEST"
-		for _, e := range castedErr {
-			switch e.Tag() {
-			case "required":
-				res.Message = fmt.Sprintf("%s is required", e.Field())
-			case "max":
-				res.Message = fmt.Sprintf("%s is should below than %s characters", e.Field(), e.Param())
-			case "min":
-				res.Message = fmt.Sprintf("%s is should more than %s characters", e.Field(), e.Param())
-			case "gte":
-				res.Message = fmt.Sprintf("%s is should greater than %s", e.Field(), e.Param())
-			}
-		}
+		res.Code = http.StatusUnprocessableEntity
+		res.Status = "UNPROCESSABLE ENTITY"
+		res.Errors = newFieldErrors(castedErr, trans)
+		res.Message = res.Errors[0].Message
+	} else if castedErr, ok := err.(*echo.HTTPError); ok {
+		res.Code = castedErr.Code
+		res.Status = strings.ToUpper(http.StatusText(castedErr.Code))
+		res.Message = fmt.Sprint(castedErr.Message)
 	} else {
 		res.Code = http.StatusInternalServerError
 		res.Status = "FAIL"
-		res.Message = err.Error()
+		res.Message = internalErrorMessage
 	}
 
-	c.Logger().Error(err)
-	c.JSON(res.Code, res)
+	return res
+}
+
+func newFieldErrors(errs validator.ValidationErrors, trans ut.Translator) []web.FieldError {
+	fieldErrors := make([]web.FieldError, 0, len(errs))
+	for _, e := range errs {
+		// The namespace starts with the name of the validated struct.
+		_, field, _ := strings.Cut(e.Namespace(), ".")
+		fieldErrors = append(fieldErrors, web.FieldError{
+			Field:   field,
+			Rule:    e.Tag(),
+			Param:   e.Param(),
+			Message: helper.TranslateFieldError(e, trans),
+		})
+	}
+	return fieldErrors
 }
@@ -0,0 +1,47 @@
+package exception
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+)
+
+// The formats errors can be sent in, set with ERROR_FORMAT.
+const (
+	ErrorFormatDefault = "default"
+	ErrorFormatProblem = "problem"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+func acceptsProblem(request *http.Request) bool {
+	return strings.Contains(request.Header.Get(echo.HeaderAccept), MIMEApplicationProblemJSON)
+}
+
+// NewProblemDetails turns an error response into an RFC 7807 document about
+// the request of c. Errors have no documentation of their own, so the type is
+// about:blank and the title the reason phrase of the status.
+func NewProblemDetails(res web.ErrorResponse, c echo.Context) web.ProblemDetails {
+	title := http.StatusText(res.Code)
+	if res.Code == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		requestID = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+
+	return web.ProblemDetails{
+		Type:      "about:blank",
+		Title:     title,
+		Status:    res.Code,
+		Detail:    res.Message,
+		Instance:  c.Request().URL.RequestURI(),
+		Errors:    res.Errors,
+		Details:   res.Details,
+		RequestID: requestID,
+	}
+}
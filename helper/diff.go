@@ -0,0 +1,257 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// MaxDiffLines is the most lines either side of a diff may have. The time a
+// diff takes grows with the lines times the changes, so longer texts are
+// refused.
+const MaxDiffLines = 5000
+
+var ErrDiffTooLarge = fmt.Errorf("text has more than %d lines", MaxDiffLines)
+
+type diffLine struct {
+	Kind byte
+	Text string
+	A    int
+	B    int
+}
+
+// UnifiedDiff compares a and b line by line and returns the changes in unified
+// diff format with three lines of context. It returns "" when both are equal
+// and ErrDiffTooLarge when either has more than MaxDiffLines lines.
+func UnifiedDiff(fromLabel string, toLabel string, a string, b string) (string, error) {
+	if strings.Count(a, "\n") >= MaxDiffLines || strings.Count(b, "\n") >= MaxDiffLines {
+		return "", ErrDiffTooLarge
+	}
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == ' ' {
+			i++
+			continue
+		}
+
+		start := max(i-diffContext, 0)
+		last := i
+		for j := i; j < len(lines) && j-last <= 2*diffContext; j++ {
+			if lines[j].Kind != ' ' {
+				last = j
+			}
+		}
+		end := min(last+diffContext+1, len(lines))
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromLabel, toLabel)
+		}
+		writeHunk(&sb, lines[start:end])
+		i = end
+	}
+
+	return sb.String(), nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns the lines of a and b in order, marking the ones only in a
+// with '-' and the ones only in b with '+'. A and B hold the position in a and
+// b before the line, which the hunk headers are built from.
+func diffLines(a []string, b []string) []diffLine {
+	d := differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+
+	lines := make([]diffLine, 0, len(d.ops))
+	i, j := 0, 0
+	for k := 0; k < len(d.ops); {
+		if d.ops[k] == ' ' {
+			lines = append(lines, diffLine{Kind: ' ', Text: a[i], A: i, B: j})
+			i++
+			j++
+			k++
+			continue
+		}
+		// Within a run of changes deletions come first, whichever order the
+		// split found them in.
+		var deleted, inserted int
+		for ; k < len(d.ops) && d.ops[k] != ' '; k++ {
+			if d.ops[k] == '-' {
+				deleted++
+			} else {
+				inserted++
+			}
+		}
+		for ; deleted > 0; deleted-- {
+			lines = append(lines, diffLine{Kind: '-', Text: a[i], A: i, B: j})
+			i++
+		}
+		for ; inserted > 0; inserted-- {
+			lines = append(lines, diffLine{Kind: '+', Text: b[j], A: i, B: j})
+			j++
+		}
+	}
+
+	return lines
+}
+
+// differ finds a shortest edit script with Myers' algorithm in linear space:
+// the middle of the path is found from both ends and the halves are compared
+// on their own, so time is O((N+M)·D) and memory O(N+M).
+type differ struct {
+	a   []string
+	b   []string
+	ops []byte
+}
+
+func (d *differ) compare(aLo int, aHi int, bLo int, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, ' ')
+		aLo++
+		bLo++
+	}
+	var suffix int
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+		suffix++
+	}
+
+	switch {
+	case aLo == aHi:
+		d.repeat('+', bHi-bLo)
+	case bLo == bHi:
+		d.repeat('-', aHi-aLo)
+	default:
+		x, y := d.bisect(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	}
+
+	d.repeat(' ', suffix)
+}
+
+func (d *differ) repeat(op byte, count int) {
+	for ; count > 0; count-- {
+		d.ops = append(d.ops, op)
+	}
+}
+
+// bisect walks the edit graph forwards from the start and backwards from the
+// end until both paths overlap, and returns the point they meet at. Paths
+// that leave the graph stop being followed.
+func (d *differ) bisect(aLo int, aHi int, bLo int, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	// With an odd delta the paths can only meet on a forward step.
+	odd := delta%2 != 0
+	var fStart, fEnd, bStart, bEnd int
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				bk := offset + delta - k
+				if bk >= 0 && bk < len(backward) && backward[bk] != -1 && x >= n-backward[bk] {
+					return aLo + x, bLo + y
+				}
+			}
+		}
+
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				fk := offset + delta - k
+				if fk >= 0 && fk < len(forward) && forward[fk] != -1 && forward[fk] >= n-x {
+					fx := forward[fk]
+					return aLo + fx, bLo + fx - (fk - offset)
+				}
+			}
+		}
+	}
+
+	// Not reached for a and b of any size, the paths always meet by maxD.
+	return aHi, bLo
+}
+
+func writeHunk(sb *strings.Builder, lines []diffLine) {
+	var aCount, bCount int
+	for _, line := range lines {
+		if line.Kind != '+' {
+			aCount++
+		}
+		if line.Kind != '-' {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(lines[0].A, aCount), hunkRange(lines[0].B, bCount))
+	for _, line := range lines {
+		sb.WriteByte(line.Kind)
+		sb.WriteString(line.Text)
+		sb.WriteByte('\n')
+	}
+}
+
+func hunkRange(start int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+var ErrInvalidETag = errors.New("entity tag is invalid")
+
+// ETag formats a resource version as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch returns the versions an If-Match header lists, or nil for "*".
+// If-Match uses strong comparison, so weak tags are rejected.
+func ParseIfMatch(header string) ([]int, error) {
+	if strings.TrimSpace(header) == "*" {
+		return nil, nil
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return nil, ErrInvalidETag
+		}
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil || version < 1 {
+			return nil, ErrInvalidETag
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// MatchIfNoneMatch reports whether an If-None-Match header lists the tag of
+// version. It uses weak comparison, so W/"3" matches version 3 as well.
+func MatchIfNoneMatch(header string, version int) bool {
+	etag := ETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
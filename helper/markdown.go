@@ -0,0 +1,74 @@
+package helper
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const ExcerptLength = 200
+
+// Raw HTML in the source is dropped by goldmark already, the policy removes
+// whatever else could run script, like javascript: links.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+var htmlPolicy = newHTMLPolicy()
+
+var textPolicy = bluemonday.StrictPolicy()
+
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	// Fenced code blocks keep their language-* class for client side
+	// highlighting.
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	return policy
+}
+
+// RenderHTML renders a note body as sanitized HTML. Plain bodies are escaped,
+// blank lines separate paragraphs and single line breaks are kept.
+func RenderHTML(format string, body string) string {
+	if format != web.NoteFormatMarkdown {
+		var sb strings.Builder
+		for _, paragraph := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n\n") {
+			if paragraph = strings.Trim(paragraph, "\n"); paragraph == "" {
+				continue
+			}
+			lines := strings.Split(html.EscapeString(paragraph), "\n")
+			sb.WriteString("<p>" + strings.Join(lines, "<br>\n") + "</p>\n")
+		}
+		return sb.String()
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(body), &buf); err != nil {
+		return html.EscapeString(body)
+	}
+	return htmlPolicy.Sanitize(buf.String())
+}
+
+// Excerpt returns the start of a note body as plain text, with the Markdown
+// removed and whitespace collapsed, cut at a word boundary.
+func Excerpt(format string, body string) string {
+	text := body
+	if format == web.NoteFormatMarkdown {
+		text = html.UnescapeString(textPolicy.Sanitize(RenderHTML(format, body)))
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= ExcerptLength {
+		return text
+	}
+
+	runes := []rune(text)[:ExcerptLength]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, ' '); i > ExcerptLength/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:") + "…"
+}
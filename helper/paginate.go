@@ -1,17 +1,102 @@
 package helper
 
-import "gorm.io/gorm"
+import (
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+func NormalizePage(page int, pageSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	return page, pageSize
+}
 
 func Paginate(page int, pageSize int) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
-		if page <= 0 {
-			page = 1
-		}
-		if pageSize <= 0 {
-			pageSize = 1
-		}
+		page, pageSize := NormalizePage(page, pageSize)
 
 		offset := (page - 1) * pageSize
 		return db.Offset(offset).Limit(pageSize)
 	}
 }
+
+func NewPageMeta(page int, pageSize int, totalItems int64) *web.PageMeta {
+	page, pageSize = NormalizePage(page, pageSize)
+
+	return &web.PageMeta{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalItems: totalItems,
+		TotalPages: int(math.Ceil(float64(totalItems) / float64(pageSize))),
+	}
+}
+
+// NewPageLinks builds navigation links from the request URL, keeping every
+// other query parameter (filters, sort) as the client sent it.
+func NewPageLinks(requestUrl *url.URL, meta *web.PageMeta) *web.PageLinks {
+	pageUrl := func(page int) string {
+		u := *requestUrl
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("pageSize", strconv.Itoa(meta.PageSize))
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	lastPage := meta.TotalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	links := &web.PageLinks{
+		Self:  pageUrl(meta.Page),
+		First: pageUrl(1),
+		Last:  pageUrl(lastPage),
+	}
+	if meta.Page < meta.TotalPages {
+		links.Next = pageUrl(meta.Page + 1)
+	}
+	if meta.Page > 1 {
+		links.Prev = pageUrl(min(meta.Page-1, lastPage))
+	}
+
+	return links
+}
+
+func NewCursorLinks(requestUrl *url.URL, meta *web.CursorMeta) *web.PageLinks {
+	cursorUrl := func(cursor string) string {
+		u := *requestUrl
+		query := u.Query()
+		query.Del("page")
+		query.Set("cursor", cursor)
+		query.Set("pageSize", strconv.Itoa(meta.PageSize))
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	links := &web.PageLinks{
+		Self:  requestUrl.String(),
+		First: cursorUrl(""),
+	}
+	if meta.NextCursor != "" {
+		links.Next = cursorUrl(meta.NextCursor)
+	}
+
+	return links
+}
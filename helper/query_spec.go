@@ -0,0 +1,150 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	FilterEqual    = "eq"
+	FilterContains = "contains"
+	FilterAfter    = "after"
+	FilterBefore   = "before"
+)
+
+// QueryFields is an allowlist mapping the field names clients may filter or
+// sort by to the SQL column backing them.
+type QueryFields map[string]string
+
+type FilterSpec struct {
+	Field    string
+	Operator string
+	Value    interface{}
+}
+
+type SortSpec struct {
+	Field string
+	Desc  bool
+}
+
+// TagFilter keeps notes tagged with any of Names, or with all of them when
+// All is set.
+type TagFilter struct {
+	Names []string
+	All   bool
+}
+
+type QuerySpec struct {
+	Page     int
+	PageSize int
+	Filters  []FilterSpec
+	Sorts    []SortSpec
+	Tags     TagFilter
+	// CategoryTree keeps notes in this category or any category below it.
+	CategoryTree int
+	Keyset       bool
+	After        *Cursor
+}
+
+// ParseSort reads a sort parameter such as "-updated_at,title", where a
+// leading "-" means descending order.
+func ParseSort(raw string) []SortSpec {
+	var sorts []SortSpec
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "-" {
+			continue
+		}
+		if strings.HasPrefix(part, "-") {
+			sorts = append(sorts, SortSpec{Field: part[1:], Desc: true})
+		} else {
+			sorts = append(sorts, SortSpec{Field: part})
+		}
+	}
+	return sorts
+}
+
+func (q QuerySpec) Validate(fields QueryFields) error {
+	for _, f := range q.Filters {
+		if _, ok := fields[f.Field]; !ok {
+			return fmt.Errorf("cannot filter by %s", f.Field)
+		}
+		switch f.Operator {
+		case FilterEqual, FilterContains, FilterAfter, FilterBefore:
+		default:
+			return fmt.Errorf("unknown filter operator %s", f.Operator)
+		}
+	}
+	for _, s := range q.Sorts {
+		if _, ok := fields[s.Field]; !ok {
+			return fmt.Errorf("cannot sort by %s", s.Field)
+		}
+	}
+	if q.After != nil && q.After.Sort != SortSignature(q.Sorts) {
+		return errors.New("cursor does not match the requested sort")
+	}
+
+	return nil
+}
+
+func Filter(filters []FilterSpec, fields QueryFields) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, f := range filters {
+			column, ok := fields[f.Field]
+			if !ok {
+				continue
+			}
+			switch f.Operator {
+			case FilterEqual:
+				db = db.Where(column+" = ?", f.Value)
+			case FilterContains:
+				db = db.Where(column+" ILIKE ?", "%"+escapeLike(fmt.Sprint(f.Value))+"%")
+			case FilterAfter:
+				db = db.Where(column+" > ?", f.Value)
+			case FilterBefore:
+				db = db.Where(column+" < ?", f.Value)
+			}
+		}
+		return db
+	}
+}
+
+// Sort orders by the requested fields and always finishes with the "id"
+// field so that pages stay stable when the sort keys are not unique.
+func Sort(sorts []SortSpec, fields QueryFields) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, s := range keysetSorts(sorts, fields) {
+			if s.Desc {
+				db = db.Order(fields[s.Field] + " desc")
+			} else {
+				db = db.Order(fields[s.Field] + " asc")
+			}
+		}
+		return db
+	}
+}
+
+func keysetSorts(sorts []SortSpec, fields QueryFields) []SortSpec {
+	var keys []SortSpec
+	hasId := false
+	for _, s := range sorts {
+		if _, ok := fields[s.Field]; !ok {
+			continue
+		}
+		if s.Field == "id" {
+			hasId = true
+		}
+		keys = append(keys, s)
+	}
+	if _, ok := fields["id"]; ok && !hasId {
+		keys = append(keys, SortSpec{Field: "id"})
+	}
+	return keys
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
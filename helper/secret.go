@@ -0,0 +1,15 @@
+package helper
+
+import "crypto/rand"
+
+// signingKey turns a configured secret into a signing key. An empty secret
+// gets a random key, so whatever is signed with it won't verify after a
+// restart or on another instance; serve refuses to start that way.
+func signingKey(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
@@ -0,0 +1,108 @@
+package helper
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+)
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+
+	UserIDContextKey = "userID"
+	RoleContextKey   = "role"
+	ScopesContextKey = "scopes"
+)
+
+var ErrInvalidToken = errors.New("token is invalid or expired")
+
+type TokenClaims struct {
+	UserID    int    `json:"uid"`
+	Role      string `json:"role"`
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenManager signs tokens with HS256. When the secret is empty a random
+// one is generated, so issued tokens won't survive a restart.
+func NewTokenManager(secret string, accessTTL time.Duration, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     signingKey(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (m *TokenManager) Generate(userID int, role string) (web.TokenResponse, error) {
+	var tokens web.TokenResponse
+	accessToken, errAccess := m.sign(userID, role, AccessToken, m.accessTTL)
+	if errAccess != nil {
+		return tokens, errAccess
+	}
+	refreshToken, errRefresh := m.sign(userID, role, RefreshToken, m.refreshTTL)
+	if errRefresh != nil {
+		return tokens, errRefresh
+	}
+
+	tokens = web.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(m.accessTTL.Seconds()),
+	}
+	return tokens, nil
+}
+
+func (m *TokenManager) Parse(token string, tokenType string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !parsed.Valid || claims.TokenType != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (m *TokenManager) sign(userID int, role string, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		UserID:    userID,
+		Role:      role,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+func GetUserID(c echo.Context) int {
+	userID, _ := c.Get(UserIDContextKey).(int)
+	return userID
+}
+
+func GetRole(c echo.Context) string {
+	role, _ := c.Get(RoleContextKey).(string)
+	return role
+}
+
+// GetScopes reports the scopes of the API key used for the request. The second
+// value is false when the request was authenticated with a user token.
+func GetScopes(c echo.Context) ([]string, bool) {
+	scopes, ok := c.Get(ScopesContextKey).([]string)
+	return scopes, ok
+}
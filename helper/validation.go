@@ -0,0 +1,130 @@
+package helper
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	idTranslations "github.com/go-playground/validator/v10/translations/id"
+)
+
+// ruleFailedKey is the message of tags that have no translation of their own.
+const ruleFailedKey = "rule_failed"
+
+const defaultLocale = "en"
+
+// translators holds the locales validation messages can be sent in.
+var translators = map[string]ut.Translator{
+	"en": sharedTranslator{ut.New(en.New()).GetFallback()},
+	"id": sharedTranslator{ut.New(id.New()).GetFallback()},
+}
+
+// sharedTranslator always overrides messages, as every validator registers
+// its messages again on the same translators.
+type sharedTranslator struct {
+	ut.Translator
+}
+
+func (t sharedTranslator) Add(key interface{}, text string, override bool) error {
+	return t.Translator.Add(key, text, true)
+}
+
+func (t sharedTranslator) AddCardinal(key interface{}, text string, rule locales.PluralRule, override bool) error {
+	return t.Translator.AddCardinal(key, text, rule, true)
+}
+
+func (t sharedTranslator) AddOrdinal(key interface{}, text string, rule locales.PluralRule, override bool) error {
+	return t.Translator.AddOrdinal(key, text, rule, true)
+}
+
+func (t sharedTranslator) AddRange(key interface{}, text string, rule locales.PluralRule, override bool) error {
+	return t.Translator.AddRange(key, text, rule, true)
+}
+
+var defaultTranslations = map[string]func(v *validator.Validate, trans ut.Translator) error{
+	"en": enTranslations.RegisterDefaultTranslations,
+	"id": idTranslations.RegisterDefaultTranslations,
+}
+
+var ruleFailedMessages = map[string]string{
+	"en": "{0} failed on the {1} rule",
+	"id": "{0} tidak memenuhi aturan {1}",
+}
+
+// NewValidator returns a validator that names fields after their json tag and
+// knows the messages of the built-in tags in every locale of translators.
+func NewValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	for locale, register := range defaultTranslations {
+		trans := translators[locale]
+		if err := register(validate, trans); err != nil {
+			panic(err)
+		}
+		if err := trans.Add(ruleFailedKey, ruleFailedMessages[locale], true); err != nil {
+			panic(err)
+		}
+	}
+	return validate
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// RegisterTranslation adds the message of a custom tag, messages being keyed
+// by locale. {0} is replaced by the field and {1} by the param of the tag.
+func RegisterTranslation(validate *validator.Validate, tag string, messages map[string]string) error {
+	for locale, message := range messages {
+		trans, ok := translators[locale]
+		if !ok {
+			continue
+		}
+		errRegister := validate.RegisterTranslation(tag, trans,
+			func(trans ut.Translator) error {
+				return trans.Add(tag, message, true)
+			},
+			func(trans ut.Translator, fe validator.FieldError) string {
+				text, _ := trans.T(tag, fe.Field(), fe.Param())
+				return text
+			})
+		if errRegister != nil {
+			return errRegister
+		}
+	}
+	return nil
+}
+
+// Translator returns the translator of the first locale of an Accept-Language
+// header that validation messages exist in, or the english one.
+func Translator(acceptLanguage string) ut.Translator {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		locale, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if trans, ok := translators[locale]; ok {
+			return trans
+		}
+	}
+	return translators[defaultLocale]
+}
+
+// TranslateFieldError returns the message of fe in the locale of trans.
+func TranslateFieldError(fe validator.FieldError, trans ut.Translator) string {
+	if message := fe.Translate(trans); message != fe.Error() {
+		return message
+	}
+	message, errTranslate := trans.T(ruleFailedKey, fe.Field(), fe.Tag())
+	if errTranslate != nil {
+		return fe.Error()
+	}
+	return message
+}
@@ -1,10 +1,9 @@
 package main
 
 import (
-	"github.com/go-playground/validator/v10"
-	"github.com/naomigrain/echo-crud-notes/app/database"
-	"github.com/naomigrain/echo-crud-notes/app/router"
-	"github.com/naomigrain/echo-crud-notes/config"
+	"os"
+
+	"github.com/naomigrain/echo-crud-notes/app/cli"
 )
 
 type WebResponse struct {
@@ -14,21 +13,5 @@ type WebResponse struct {
 }
 
 func main() {
-	dbConfig := config.GetDBConfig(true)
-	db, errConn := database.StartConnection(dbConfig)
-	if errConn != nil {
-		panic(errConn)
-	}
-	database.DropAll(db)
-	database.Migrate(db)
-	categoryList := database.CategorySeeder(db, 3)
-	database.NoteSeeder(db, categoryList, 5)
-
-	validate := validator.New()
-
-	e := router.InitializeEcho()
-	router.AssignRouter(e, db, validate)
-
-	port := config.GetAppConfig(true).AppPort
-	e.Logger.Fatal(e.Start(":" + port))
+	os.Exit(cli.New(os.Stdin, os.Stdout, os.Stderr).Run(os.Args[1:]))
 }
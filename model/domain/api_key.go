@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+const (
+	ScopeNotesRead       = "notes:read"
+	ScopeNotesWrite      = "notes:write"
+	ScopeCategoriesRead  = "categories:read"
+	ScopeCategoriesWrite = "categories:write"
+)
+
+type APIKey struct {
+	ID         int    `gorm:"primaryKey"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Prefix     string `gorm:"type:varchar(16);not null"`
+	KeyHash    string `gorm:"type:char(64);not null;uniqueIndex"`
+	Scopes     string `gorm:"type:varchar(255);not null"`
+	UserID     int    `gorm:"not null;index"`
+	User       User
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -1,6 +1,20 @@
 package domain
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Category struct {
-	ID   int    `gorm:"primaryKey"`
-	Name string `gorm:"type:varchar(100);not null"`
+	ID        int       `gorm:"primaryKey"`
+	Name      string    `gorm:"type:varchar(100);not null"`
+	ParentID  *int      `gorm:"index"`
+	Parent    *Category `gorm:"constraint:OnDelete:SET NULL"`
+	UserID    int       `gorm:"not null;index"`
+	User      User
+	Version   int `gorm:"not null;default:1"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
@@ -1,16 +1,39 @@
 package domain
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Note struct {
 	ID         int    `gorm:"primaryKey"`
 	Title      string `gorm:"type:varchar(100);not null"`
-	Body       string `gorm:"type:varchar(255);not null"`
+	Body       string `gorm:"type:text;not null"`
+	Format     string `gorm:"type:varchar(16);not null;default:plain"`
 	CategoryID int
 	Category   Category
+	UserID     int `gorm:"not null;index"`
+	User       User
+	Version    int `gorm:"not null;default:1"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
 }
 
 type ScanNote struct {
-	ID       int
-	Title    string
-	Body     string
-	Category string
+	ID            int
+	Title         string
+	Body          string
+	Format        string
+	CategoryID    int
+	Category      string
+	Tags          string
+	Version       int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     *time.Time
+	Rank          float64
+	Snippet       string
+	BodyTruncated bool
 }
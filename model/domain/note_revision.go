@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+type NoteRevision struct {
+	ID         int    `gorm:"primaryKey"`
+	NoteID     int    `gorm:"not null;uniqueIndex:idx_note_revisions_note_revision"`
+	Note       Note   `gorm:"constraint:OnDelete:CASCADE"`
+	Revision   int    `gorm:"not null;uniqueIndex:idx_note_revisions_note_revision"`
+	Title      string `gorm:"type:varchar(100);not null"`
+	Body       string `gorm:"type:text;not null"`
+	Format     string `gorm:"type:varchar(16);not null;default:plain"`
+	CategoryID int    `gorm:"not null"`
+	AuthorID   int    `gorm:"not null"`
+	CreatedAt  time.Time
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+type Tag struct {
+	ID        int    `gorm:"primaryKey"`
+	Name      string `gorm:"type:varchar(50);not null;uniqueIndex:idx_tags_user_name"`
+	UserID    int    `gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	User      User
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type NoteTag struct {
+	NoteID int  `gorm:"primaryKey"`
+	Note   Note `gorm:"constraint:OnDelete:CASCADE"`
+	TagID  int  `gorm:"primaryKey;index"`
+	Tag    Tag  `gorm:"constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+type User struct {
+	ID        int    `gorm:"primaryKey"`
+	Email     string `gorm:"type:varchar(255);not null;uniqueIndex"`
+	Password  string `gorm:"type:varchar(255);not null"`
+	Role      string `gorm:"type:varchar(20);not null;default:editor"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
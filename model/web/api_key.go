@@ -0,0 +1,19 @@
+package web
+
+import "time"
+
+type APIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,min=2,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=notes:read notes:write categories:read categories:write"`
+}
+
+type APIKeyResponse struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
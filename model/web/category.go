@@ -1,6 +1,48 @@
 package web
 
+import "time"
+
 type CategoryJSON struct {
-	ID   int    `json:"id"`
-	Name string `json:"name" validate:"required,min=2,max=100"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name" validate:"required,min=2,max=100"`
+	ParentID  *int      `json:"parent_id" validate:"omitempty,min=1"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Versions  []int     `json:"-"`
+}
+
+type CategoryTreeResponse struct {
+	ID       int                    `json:"id"`
+	Name     string                 `json:"name"`
+	ParentID *int                   `json:"parent_id"`
+	Version  int                    `json:"version"`
+	Children []CategoryTreeResponse `json:"children"`
+}
+
+const (
+	CategoryDeleteRestrict = "restrict"
+	CategoryDeleteCascade  = "cascade"
+	CategoryDeleteReassign = "reassign"
+)
+
+// CategoryDeleteRequest says what happens to the notes and subcategories of a
+// deleted category. TargetID is only used by the reassign strategy.
+type CategoryDeleteRequest struct {
+	ID       int
+	Versions []int
+	Strategy string
+	TargetID int
+}
+
+type CategoryDeleteResponse struct {
+	Strategy      string `json:"strategy"`
+	Notes         int64  `json:"notes"`
+	Subcategories int64  `json:"subcategories"`
+}
+
+// CategoryUsage is sent with the conflict returned by the restrict strategy.
+type CategoryUsage struct {
+	Notes         int64 `json:"notes"`
+	Subcategories int64 `json:"subcategories"`
 }
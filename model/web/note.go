@@ -1,15 +1,81 @@
 package web
 
+import "time"
+
+const (
+	NoteFormatPlain    = "plain"
+	NoteFormatMarkdown = "markdown"
+
+	NoteRenderHTML = "html"
+)
+
 type NoteRequest struct {
-	ID         int    `json:"id"`
-	Title      string `json:"title" validate:"required,min=2,max=100"`
-	Body       string `json:"body" validate:"required,min=2,max=255"`
-	CategoryId int    `json:"id_category" validate:"required,gte=0"`
+	ID         int      `json:"id"`
+	Title      string   `json:"title" validate:"required,min=2,max=100"`
+	Body       string   `json:"body" validate:"required,min=2"`
+	Format     string   `json:"format" validate:"omitempty,oneof=plain markdown"`
+	CategoryId int      `json:"id_category" validate:"required,gte=0,category_exists"`
+	Tags       []string `json:"tags" validate:"max=20,dive,min=1,max=50"`
+	Versions   []int    `json:"-"`
 }
 
 type NoteResponse struct {
-	ID       int    `json:"id"`
-	Title    string `json:"title"`
-	Body     string `json:"body"`
-	Category string `json:"category"`
+	ID            int        `json:"id"`
+	Title         string     `json:"title"`
+	Body          string     `json:"body"`
+	Format        string     `json:"format"`
+	HTML          string     `json:"html,omitempty"`
+	Excerpt       string     `json:"excerpt,omitempty"`
+	Category      string     `json:"category"`
+	Tags          []string   `json:"tags"`
+	Version       int        `json:"version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+	Snippet       string     `json:"snippet,omitempty"`
+	BodyTruncated bool       `json:"body_truncated"`
+}
+
+const (
+	NoteBatchAtomic     = "atomic"
+	NoteBatchBestEffort = "best_effort"
+
+	NoteBatchCreate = "create"
+	NoteBatchUpdate = "update"
+	NoteBatchDelete = "delete"
+
+	NoteBatchOK         = "ok"
+	NoteBatchFailed     = "failed"
+	NoteBatchRolledBack = "rolled_back"
+)
+
+type NoteBatchRequest struct {
+	Mode       string               `json:"mode"`
+	Operations []NoteBatchOperation `json:"operations"`
+}
+
+// NoteBatchOperation is one create, update or delete of a batch. Updates and
+// deletes need the version of the note, like If-Match on the single routes.
+type NoteBatchOperation struct {
+	Op      string       `json:"op"`
+	ID      int          `json:"id"`
+	Version int          `json:"version"`
+	Note    *NoteRequest `json:"note"`
+}
+
+type NoteBatchResult struct {
+	Index  int            `json:"index"`
+	Op     string         `json:"op"`
+	ID     int            `json:"id,omitempty"`
+	Status string         `json:"status"`
+	Note   *NoteResponse  `json:"note,omitempty"`
+	Error  *ErrorResponse `json:"error,omitempty"`
+}
+
+type NoteBatchResponse struct {
+	Mode      string            `json:"mode"`
+	Committed bool              `json:"committed"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []NoteBatchResult `json:"results"`
 }
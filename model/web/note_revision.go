@@ -0,0 +1,19 @@
+package web
+
+import "time"
+
+type NoteRevisionResponse struct {
+	Revision   int       `json:"revision"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	Format     string    `json:"format"`
+	CategoryID int       `json:"id_category"`
+	AuthorID   int       `json:"author_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type NoteRevisionDiffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
@@ -0,0 +1,15 @@
+package web
+
+import "time"
+
+type TagRequest struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" validate:"required,min=1,max=50"`
+}
+
+type TagResponse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
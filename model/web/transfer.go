@@ -0,0 +1,58 @@
+package web
+
+import "time"
+
+const (
+	FormatJSONL    = "jsonl"
+	FormatCSV      = "csv"
+	FormatMarkdown = "zip-md"
+
+	RecordCategory = "category"
+	RecordNote     = "note"
+
+	ImportRemapIDs    = "remap"
+	ImportPreserveIDs = "preserve"
+)
+
+// TransferRecord is a category or a note as it is exported, and read back on
+// import. Type tells which of the fields are used.
+type TransferRecord struct {
+	Type       string    `json:"type"`
+	ID         int       `json:"id"`
+	Name       string    `json:"name,omitempty"`
+	ParentID   *int      `json:"parent_id,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	Body       string    `json:"body,omitempty"`
+	Format     string    `json:"format,omitempty"`
+	CategoryID int       `json:"category_id,omitempty"`
+	Category   string    `json:"category,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ImportRequest struct {
+	Format string
+	IDs    string
+	DryRun bool
+	Data   []byte
+}
+
+type ImportConflict struct {
+	Type    string `json:"type"`
+	ID      int    `json:"id"`
+	Message string `json:"message"`
+}
+
+type ImportCount struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+type ImportReport struct {
+	DryRun     bool             `json:"dry_run"`
+	IDs        string           `json:"ids"`
+	Categories ImportCount      `json:"categories"`
+	Notes      ImportCount      `json:"notes"`
+	Conflicts  []ImportConflict `json:"conflicts"`
+}
@@ -4,10 +4,56 @@ type WebResponse struct {
 	Code   int         `json:"code"`
 	Status string      `json:"status"`
 	Data   interface{} `json:"data"`
+	Meta   interface{} `json:"meta,omitempty"`
+	Links  *PageLinks  `json:"links,omitempty"`
+}
+
+type PageMeta struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	TotalItems int64 `json:"totalItems"`
+	TotalPages int   `json:"totalPages"`
+}
+
+type CursorMeta struct {
+	PageSize   int    `json:"pageSize"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type PageLinks struct {
+	Self  string `json:"self"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	First string `json:"first,omitempty"`
+	Last  string `json:"last,omitempty"`
 }
 
 type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Status  string `json:"status"`
+	Code    int          `json:"code"`
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Details interface{}  `json:"details,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// ProblemDetails is an RFC 7807 problem document. Errors, Details and
+// RequestID are extension members.
+type ProblemDetails struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Details   interface{}  `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError is one failed validate tag. Field is the json path of the field,
+// Rule the tag and Param its parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param"`
 	Message string `json:"message"`
 }
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	FindAll(ctx context.Context, tx *gorm.DB, userID int) ([]domain.APIKey, error)
+	FindById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.APIKey, error)
+	FindByHash(ctx context.Context, tx *gorm.DB, keyHash string) (domain.APIKey, error)
+	Save(ctx context.Context, tx *gorm.DB, apiKey domain.APIKey) (domain.APIKey, error)
+	TouchLastUsed(ctx context.Context, tx *gorm.DB, id int, usedAt time.Time) error
+}
+
+type apiKeyRepositoryImpl struct {
+}
+
+func NewAPIKeyRepository() *apiKeyRepositoryImpl {
+	return &apiKeyRepositoryImpl{}
+}
+
+func (r *apiKeyRepositoryImpl) FindAll(ctx context.Context, tx *gorm.DB, userID int) ([]domain.APIKey, error) {
+	tx = tx.WithContext(ctx)
+	var apiKeys []domain.APIKey
+	if err := tx.Where("user_id = ?", userID).Order("id asc").Find(&apiKeys).Error; err != nil {
+		return apiKeys, translateError(err)
+	}
+
+	return apiKeys, nil
+}
+
+func (r *apiKeyRepositoryImpl) FindById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.APIKey, error) {
+	tx = tx.WithContext(ctx)
+	var apiKey domain.APIKey
+	if err := tx.Where("user_id = ?", userID).First(&apiKey, id).Error; err != nil {
+		return apiKey, translateError(err)
+	}
+
+	return apiKey, nil
+}
+
+func (r *apiKeyRepositoryImpl) FindByHash(ctx context.Context, tx *gorm.DB, keyHash string) (domain.APIKey, error) {
+	tx = tx.WithContext(ctx)
+	var apiKey domain.APIKey
+	if err := tx.Preload("User").Where("key_hash = ?", keyHash).First(&apiKey).Error; err != nil {
+		return apiKey, translateError(err)
+	}
+
+	return apiKey, nil
+}
+
+func (r *apiKeyRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, apiKey domain.APIKey) (domain.APIKey, error) {
+	tx = tx.WithContext(ctx)
+	if err := tx.Omit("User").Save(&apiKey).Error; err != nil {
+		return apiKey, translateError(err)
+	}
+
+	return apiKey, nil
+}
+
+func (r *apiKeyRepositoryImpl) TouchLastUsed(ctx context.Context, tx *gorm.DB, id int, usedAt time.Time) error {
+	tx = tx.WithContext(ctx)
+	if err := tx.Model(&domain.APIKey{}).Where("id = ?", id).
+		UpdateColumn("last_used_at", usedAt).Error; err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
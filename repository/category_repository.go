@@ -1,17 +1,78 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/naomigrain/echo-crud-notes/helper"
 	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"gorm.io/gorm"
 )
 
 type CategoryRepository interface {
-	FindAll(tx *gorm.DB, page int, pageSize int) ([]domain.Category, error)
-	IsExistById(tx *gorm.DB, id int) bool
-	FindById(tx *gorm.DB, id int) (domain.Category, error)
-	Save(tx *gorm.DB, category domain.Category) (domain.Category, error)
-	Delete(tx *gorm.DB, id int) error
+	FindAll(ctx context.Context, tx *gorm.DB, userID int, spec helper.QuerySpec) ([]domain.Category, helper.PageInfo, error)
+	IsExistById(ctx context.Context, tx *gorm.DB, userID int, id int) bool
+	FindById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.Category, error)
+	FindOwnerId(ctx context.Context, tx *gorm.DB, id int) (int, error)
+	FindByIds(ctx context.Context, tx *gorm.DB, userID int, ids []int) ([]domain.Category, error)
+	FindExisting(ctx context.Context, tx *gorm.DB, ids []int) ([]domain.Category, error)
+	Insert(ctx context.Context, tx *gorm.DB, category domain.Category) (domain.Category, error)
+	SyncIdSequence(ctx context.Context, tx *gorm.DB) error
+	Save(ctx context.Context, tx *gorm.DB, category domain.Category) (domain.Category, error)
+	Delete(ctx context.Context, tx *gorm.DB, userID int, id int, version int) error
+	CountNotes(ctx context.Context, tx *gorm.DB, userID int, id int) (int64, error)
+	CountChildren(ctx context.Context, tx *gorm.DB, userID int, id int) (int64, error)
+	MoveChildren(ctx context.Context, tx *gorm.DB, userID int, fromID int, toID int) (int64, error)
+	DeleteAll(ctx context.Context, tx *gorm.DB, userID int, ids []int) (int64, error)
+	FindTree(ctx context.Context, tx *gorm.DB, userID int) ([]domain.Category, error)
+	FindSubtreeIds(ctx context.Context, tx *gorm.DB, userID int, id int) ([]int, error)
+	FindSubtreeIdsWithTrashed(ctx context.Context, tx *gorm.DB, userID int, id int) ([]int, error)
+	FindDescendants(ctx context.Context, tx *gorm.DB, userID int, id int) ([]domain.Category, error)
+	LockTree(ctx context.Context, tx *gorm.DB, userID int) error
+	Restore(ctx context.Context, tx *gorm.DB, userID int, id int) error
+	PurgeTrashed(ctx context.Context, tx *gorm.DB, before time.Time) (int64, error)
+}
+
+var CategoryQueryFields = helper.QueryFields{
+	"id":         "categories.id",
+	"name":       "categories.name",
+	"created_at": "categories.created_at",
+	"updated_at": "categories.updated_at",
+}
+
+// categorySubtree selects the id of a category and of every live category
+// below it. It takes the category id and the user id. UNION drops the ids that
+// were already found, so the query ends even if parents ever form a loop.
+const categorySubtree = `WITH RECURSIVE subtree AS (
+		SELECT id FROM categories WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+		UNION
+		SELECT categories.id FROM categories INNER JOIN subtree ON categories.parent_id = subtree.id
+		WHERE categories.deleted_at IS NULL
+	) SELECT id FROM subtree`
+
+// categorySubtreeWithTrashed is categorySubtree including the categories in
+// the trash, which keep their parent and can come back.
+const categorySubtreeWithTrashed = `WITH RECURSIVE subtree AS (
+		SELECT id FROM categories WHERE id = ? AND user_id = ?
+		UNION
+		SELECT categories.id FROM categories INNER JOIN subtree ON categories.parent_id = subtree.id
+	) SELECT id FROM subtree`
+
+// categoryTreeLock is the first key of the advisory lock taken while moving
+// categories, the second key is the user id.
+const categoryTreeLock = 1
+
+func categorySortValue(category domain.Category, field string) interface{} {
+	switch field {
+	case "name":
+		return category.Name
+	case "created_at":
+		return category.CreatedAt
+	case "updated_at":
+		return category.UpdatedAt
+	default:
+		return category.ID
+	}
 }
 
 type categoryRepositoryImpl struct {
@@ -21,50 +82,269 @@ func NewCategoryRepository() *categoryRepositoryImpl {
 	return &categoryRepositoryImpl{}
 }
 
-func (r *categoryRepositoryImpl) FindAll(tx *gorm.DB, page int, pageSize int) ([]domain.Category, error) {
+func (r *categoryRepositoryImpl) FindAll(ctx context.Context, tx *gorm.DB, userID int,
+	spec helper.QuerySpec) ([]domain.Category, helper.PageInfo, error) {
+	tx = tx.WithContext(ctx)
 	var categories []domain.Category
-	if page > 0 && pageSize > 0 {
-		if err := tx.Scopes(helper.Paginate(page, pageSize)).Find(&categories).Error; err != nil {
-			return categories, err
+	var info helper.PageInfo
+	query := func() *gorm.DB {
+		return tx.Model(&domain.Category{}).
+			Where("categories.user_id = ?", userID).
+			Scopes(helper.Filter(spec.Filters, CategoryQueryFields))
+	}
+
+	if spec.Keyset {
+		if err := query().
+			Scopes(helper.Keyset(spec.Sorts, CategoryQueryFields, spec.After, spec.PageSize)).
+			Find(&categories).Error; err != nil {
+			return categories, info, translateError(err)
 		}
-	} else {
-		if err := tx.Find(&categories).Error; err != nil {
-			return categories, err
+		if len(categories) > spec.PageSize {
+			categories = categories[:spec.PageSize]
+			last := categories[len(categories)-1]
+			info.Next = helper.NewCursor(spec.Sorts, CategoryQueryFields, func(field string) interface{} {
+				return categorySortValue(last, field)
+			})
 		}
+		return categories, info, nil
 	}
 
-	return categories, nil
+	if err := query().Count(&info.TotalItems).Error; err != nil {
+		return categories, info, translateError(err)
+	}
+	if err := query().
+		Scopes(helper.Sort(spec.Sorts, CategoryQueryFields), helper.Paginate(spec.Page, spec.PageSize)).
+		Find(&categories).Error; err != nil {
+		return categories, info, translateError(err)
+	}
+
+	return categories, info, nil
 }
 
-func (r *categoryRepositoryImpl) IsExistById(tx *gorm.DB, id int) bool {
-	if result := tx.Model(&domain.Category{}).Select("id", id).RowsAffected; result == 0 {
+func (r *categoryRepositoryImpl) IsExistById(ctx context.Context, tx *gorm.DB, userID int, id int) bool {
+	tx = tx.WithContext(ctx)
+	var count int64
+	if tx.Model(&domain.Category{}).Where("id = ? AND user_id = ?", id, userID).Count(&count); count == 0 {
 		return false
 	}
 
 	return true
 }
 
-func (r *categoryRepositoryImpl) FindById(tx *gorm.DB, id int) (domain.Category, error) {
+func (r *categoryRepositoryImpl) FindById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.Category, error) {
+	tx = tx.WithContext(ctx)
 	var category domain.Category
-	if err := tx.First(&category, id).Error; err != nil {
-		return category, err
+	if err := tx.Where("user_id = ?", userID).First(&category, id).Error; err != nil {
+		return category, translateError(err)
 	}
 
 	return category, nil
 }
 
-func (r *categoryRepositoryImpl) Save(tx *gorm.DB, category domain.Category) (domain.Category, error) {
-	if err := tx.Save(&category).Error; err != nil {
-		return category, err
+// FindOwnerId returns the id of the user a category belongs to. It isn't
+// scoped to a user, it is meant for admins acting on categories of others.
+func (r *categoryRepositoryImpl) FindOwnerId(ctx context.Context, tx *gorm.DB, id int) (int, error) {
+	tx = tx.WithContext(ctx)
+	var category domain.Category
+	if err := tx.Select("user_id").First(&category, id).Error; err != nil {
+		return 0, translateError(err)
+	}
+
+	return category.UserID, nil
+}
+
+func (r *categoryRepositoryImpl) FindByIds(ctx context.Context, tx *gorm.DB, userID int, ids []int) ([]domain.Category, error) {
+	tx = tx.WithContext(ctx)
+	var categories []domain.Category
+	if err := tx.Where("id IN ? AND user_id = ?", ids, userID).Find(&categories).Error; err != nil {
+		return categories, translateError(err)
+	}
+
+	return categories, nil
+}
+
+// FindExisting looks up categories by id across all users, including the ones
+// in the trash.
+func (r *categoryRepositoryImpl) FindExisting(ctx context.Context, tx *gorm.DB, ids []int) ([]domain.Category, error) {
+	tx = tx.WithContext(ctx)
+	var categories []domain.Category
+	if len(ids) == 0 {
+		return categories, nil
+	}
+	if err := tx.Unscoped().Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return categories, translateError(err)
+	}
+
+	return categories, nil
+}
+
+// Insert creates the category as given, keeping its id when one is set.
+func (r *categoryRepositoryImpl) Insert(ctx context.Context, tx *gorm.DB, category domain.Category) (domain.Category, error) {
+	tx = tx.WithContext(ctx)
+	category.Version = 1
+	if err := tx.Omit("Parent", "User").Create(&category).Error; err != nil {
+		return category, translateError(err)
+	}
+
+	return category, nil
+}
+
+// SyncIdSequence moves the id sequence past categories inserted with their own
+// id.
+func (r *categoryRepositoryImpl) SyncIdSequence(ctx context.Context, tx *gorm.DB) error {
+	tx = tx.WithContext(ctx)
+	return translateError(tx.Exec("SELECT setval(pg_get_serial_sequence('categories', 'id'), COALESCE((SELECT MAX(id) FROM categories), 1))").Error)
+}
+
+// Save inserts a new category. An existing category is only written when its
+// stored version still equals category.Version, and the version is bumped.
+func (r *categoryRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, category domain.Category) (domain.Category, error) {
+	tx = tx.WithContext(ctx)
+	if category.ID == 0 {
+		category.Version = 1
+		if err := tx.Omit("Parent").Create(&category).Error; err != nil {
+			return category, translateError(err)
+		}
+		return category, nil
+	}
+
+	version := category.Version
+	category.Version++
+	result := tx.Model(&category).Where("version = ?", version).
+		Select("*").Omit("User", "Parent").Updates(&category)
+	if result.Error != nil {
+		return category, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return category, ErrStaleVersion
 	}
 
 	return category, nil
 }
 
-func (r *categoryRepositoryImpl) Delete(tx *gorm.DB, id int) error {
-	if err := tx.Delete(&domain.Category{}, id).Error; err != nil {
-		return err
+func (r *categoryRepositoryImpl) Delete(ctx context.Context, tx *gorm.DB, userID int, id int, version int) error {
+	tx = tx.WithContext(ctx)
+	result := tx.Where("user_id = ? AND version = ?", userID, version).Delete(&domain.Category{}, id)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaleVersion
 	}
 
 	return nil
 }
+
+func (r *categoryRepositoryImpl) CountNotes(ctx context.Context, tx *gorm.DB, userID int, id int) (int64, error) {
+	tx = tx.WithContext(ctx)
+	var count int64
+	err := tx.Model(&domain.Note{}).Where("category_id = ? AND user_id = ?", id, userID).Count(&count).Error
+	return count, translateError(err)
+}
+
+func (r *categoryRepositoryImpl) CountChildren(ctx context.Context, tx *gorm.DB, userID int, id int) (int64, error) {
+	tx = tx.WithContext(ctx)
+	var count int64
+	err := tx.Model(&domain.Category{}).Where("parent_id = ? AND user_id = ?", id, userID).Count(&count).Error
+	return count, translateError(err)
+}
+
+// MoveChildren puts the direct subcategories of fromID below toID. Their
+// versions are bumped, so clients holding an old ETag have to read them again.
+func (r *categoryRepositoryImpl) MoveChildren(ctx context.Context, tx *gorm.DB, userID int, fromID int, toID int) (int64, error) {
+	tx = tx.WithContext(ctx)
+	result := tx.Model(&domain.Category{}).
+		Where("parent_id = ? AND user_id = ?", fromID, userID).
+		Updates(map[string]interface{}{"parent_id": toID, "version": gorm.Expr("version + 1")})
+	return result.RowsAffected, translateError(result.Error)
+}
+
+// DeleteAll moves the categories to the trash without looking at versions.
+func (r *categoryRepositoryImpl) DeleteAll(ctx context.Context, tx *gorm.DB, userID int, ids []int) (int64, error) {
+	tx = tx.WithContext(ctx)
+	result := tx.Where("id IN ? AND user_id = ?", ids, userID).Delete(&domain.Category{})
+	return result.RowsAffected, translateError(result.Error)
+}
+
+// FindTree loads every category of the user in one query, the caller nests
+// them by parent id.
+func (r *categoryRepositoryImpl) FindTree(ctx context.Context, tx *gorm.DB, userID int) ([]domain.Category, error) {
+	tx = tx.WithContext(ctx)
+	var categories []domain.Category
+	if err := tx.Where("user_id = ?", userID).Order("name, id").Find(&categories).Error; err != nil {
+		return categories, translateError(err)
+	}
+
+	return categories, nil
+}
+
+// FindSubtreeIds returns id together with the ids of all its descendants.
+func (r *categoryRepositoryImpl) FindSubtreeIds(ctx context.Context, tx *gorm.DB, userID int, id int) ([]int, error) {
+	tx = tx.WithContext(ctx)
+	var ids []int
+	if err := tx.Raw(categorySubtree, id, userID).Scan(&ids).Error; err != nil {
+		return ids, translateError(err)
+	}
+
+	return ids, nil
+}
+
+// FindSubtreeIdsWithTrashed is FindSubtreeIds including the trashed
+// categories below id, so moves can't close a loop through the trash.
+func (r *categoryRepositoryImpl) FindSubtreeIdsWithTrashed(ctx context.Context, tx *gorm.DB, userID int, id int) ([]int, error) {
+	tx = tx.WithContext(ctx)
+	var ids []int
+	if err := tx.Raw(categorySubtreeWithTrashed, id, userID).Scan(&ids).Error; err != nil {
+		return ids, translateError(err)
+	}
+
+	return ids, nil
+}
+
+func (r *categoryRepositoryImpl) FindDescendants(ctx context.Context, tx *gorm.DB, userID int, id int) ([]domain.Category, error) {
+	tx = tx.WithContext(ctx)
+	var categories []domain.Category
+	if err := tx.Where("id IN ("+categorySubtree+") AND id <> ?", id, userID, id).
+		Order("name, id").
+		Find(&categories).Error; err != nil {
+		return categories, translateError(err)
+	}
+
+	return categories, nil
+}
+
+// LockTree serializes changes to the category tree of a user until the
+// transaction ends, so two concurrent moves can't form a cycle together.
+func (r *categoryRepositoryImpl) LockTree(ctx context.Context, tx *gorm.DB, userID int) error {
+	tx = tx.WithContext(ctx)
+	return translateError(tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", categoryTreeLock, userID).Error)
+}
+
+// Restore takes a category out of the trash. If its parent is still in the
+// trash the category comes back at the top level instead.
+func (r *categoryRepositoryImpl) Restore(ctx context.Context, tx *gorm.DB, userID int, id int) error {
+	tx = tx.WithContext(ctx)
+	if err := tx.Unscoped().Model(&domain.Category{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"parent_id": gorm.Expr(`(SELECT parent.id FROM categories parent
+				WHERE parent.id = categories.parent_id AND parent.deleted_at IS NULL)`),
+			"version": gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
+
+// PurgeTrashed skips categories that are still referenced by a note, including
+// notes in the trash, so restoring a note can bring its category back as well.
+func (r *categoryRepositoryImpl) PurgeTrashed(ctx context.Context, tx *gorm.DB, before time.Time) (int64, error) {
+	tx = tx.WithContext(ctx)
+	result := tx.Unscoped().
+		Where("deleted_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM notes WHERE notes.category_id = categories.id)").
+		Delete(&domain.Category{})
+	return result.RowsAffected, translateError(result.Error)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"gorm.io/gorm"
+)
+
+// ErrStaleVersion is returned when a row was changed by someone else after it
+// was read, so the write based on the old version is refused.
+var ErrStaleVersion = errors.New("record was modified by another request")
+
+// SQLSTATE codes of the constraint violations translateError knows.
+const (
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+	codeCheckViolation      = "23514"
+)
+
+// tableEntities names the rows of a table in messages sent to clients.
+var tableEntities = map[string]string{
+	"users":          "user",
+	"api_keys":       "api key",
+	"categories":     "category",
+	"notes":          "note",
+	"note_revisions": "note revision",
+	"tags":           "tag",
+	"note_tags":      "note tag",
+}
+
+// translateError turns errors of the database into the errors of the exception
+// package. Their messages only name the entity, the error of the driver is
+// kept inside for the logs. Other errors are returned as is.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &exception.NotFoundError{Entity: "record", Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	entity, ok := tableEntities[pgErr.TableName]
+	if !ok {
+		entity = "record"
+	}
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		return &exception.ConflictError{Message: entity + " already exists", Err: err}
+	case codeForeignKeyViolation:
+		// The table of the error is always the referencing one; deleting a
+		// row it points to is reported as "update or delete on table ...".
+		if strings.HasPrefix(pgErr.Message, "update or delete") {
+			return &exception.ConflictError{Message: "record is still referenced by a " + entity, Err: err}
+		}
+		return &exception.ReferenceError{Message: entity + " references a record that does not exist", Err: err}
+	case codeCheckViolation:
+		return &exception.CheckError{Message: entity + " breaks a rule of the database", Err: err}
+	}
+	return err
+}
@@ -1,17 +1,141 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
 	"github.com/naomigrain/echo-crud-notes/helper"
 	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"gorm.io/gorm"
 )
 
 type NoteRepository interface {
-	FindAll(tx *gorm.DB, page int, pageSize int) ([]domain.ScanNote, error)
-	IsExistById(tx *gorm.DB, id int) bool
-	FindById(tx *gorm.DB, id int) (domain.ScanNote, error)
-	Save(tx *gorm.DB, note domain.Note) (domain.Note, error)
-	Delete(tx *gorm.DB, id int) error
+	FindAll(ctx context.Context, tx *gorm.DB, userID int, spec helper.QuerySpec) ([]domain.ScanNote, helper.PageInfo, error)
+	Search(ctx context.Context, tx *gorm.DB, userID int, keyword string, spec helper.QuerySpec) ([]domain.ScanNote, helper.PageInfo, error)
+	IsExistById(ctx context.Context, tx *gorm.DB, userID int, id int) bool
+	FindById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.ScanNote, error)
+	Save(ctx context.Context, tx *gorm.DB, note domain.Note) (domain.Note, error)
+	Delete(ctx context.Context, tx *gorm.DB, userID int, id int, version int) error
+	FindTrash(ctx context.Context, tx *gorm.DB, userID int, spec helper.QuerySpec) ([]domain.ScanNote, helper.PageInfo, error)
+	FindTrashedById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.Note, error)
+	Restore(ctx context.Context, tx *gorm.DB, userID int, id int) error
+	Purge(ctx context.Context, tx *gorm.DB, userID int, id int) error
+	PurgeTrashed(ctx context.Context, tx *gorm.DB, before time.Time) (int64, error)
+	ReplaceTags(ctx context.Context, tx *gorm.DB, noteID int, tagIDs []int) error
+	DeleteByCategories(ctx context.Context, tx *gorm.DB, userID int, categoryIDs []int) (int64, error)
+	MoveToCategory(ctx context.Context, tx *gorm.DB, userID int, fromID int, toID int) (int64, error)
+	CreateAll(ctx context.Context, tx *gorm.DB, notes []domain.Note) ([]domain.Note, error)
+	AddTags(ctx context.Context, tx *gorm.DB, noteTags []domain.NoteTag) error
+	StreamAll(ctx context.Context, tx *gorm.DB, userID int, fn func(note domain.ScanNote) error) error
+	FindExisting(ctx context.Context, tx *gorm.DB, ids []int) ([]domain.Note, error)
+	SyncIdSequence(ctx context.Context, tx *gorm.DB) error
+}
+
+var NoteQueryFields = helper.QueryFields{
+	"id":          "notes.id",
+	"title":       "notes.title",
+	"category_id": "notes.category_id",
+	"created_at":  "notes.created_at",
+	"updated_at":  "notes.updated_at",
+}
+
+// NoteTrashQueryFields adds deleted_at, which is only meaningful for notes in
+// the trash.
+var NoteTrashQueryFields = helper.QueryFields{
+	"id":          "notes.id",
+	"title":       "notes.title",
+	"category_id": "notes.category_id",
+	"created_at":  "notes.created_at",
+	"updated_at":  "notes.updated_at",
+	"deleted_at":  "notes.deleted_at",
+}
+
+// noteBatchSize is the number of rows sent per INSERT when creating many
+// notes at once.
+const noteBatchSize = 100
+
+// noteSearchDocument must stay in sync with the idx_notes_search expression
+// index created by the first migration, otherwise Postgres won't use the index.
+const noteSearchDocument = "to_tsvector('english', notes.title || ' ' || notes.body)"
+
+// snippetStart and snippetStop mark the matches in search snippets. Control
+// characters can't be confused with the text of a note, so the text is HTML
+// escaped first and the marks are then turned into <mark> tags.
+const (
+	snippetStart = "\x01"
+	snippetStop  = "\x02"
+)
+
+// snippetOptions is used on the same text as noteSearchDocument, so a note
+// that only matches in its title still gets a marked snippet.
+const snippetOptions = "StartSel=" + snippetStart + ", StopSel=" + snippetStop + ", MaxFragments=2"
+
+var snippetMarks = strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>")
+
+// markSnippet escapes a headline of ts_headline and highlights its matches.
+func markSnippet(headline string) string {
+	return snippetMarks.Replace(html.EscapeString(headline))
+}
+
+// NoteListBodyLength is the number of characters of the body that lists
+// return, the whole body is only read for a single note.
+const NoteListBodyLength = 1000
+
+// noteColumns aggregates the tag names of every note in the same statement, so
+// listing a page of notes never needs a query per note.
+const noteColumns = `notes.format, notes.category_id, categories.name as category,
+	notes.version, notes.created_at, notes.updated_at, notes.deleted_at,
+	COALESCE((SELECT string_agg(tags.name, ',' ORDER BY tags.name) FROM note_tags
+		INNER JOIN tags ON tags.id = note_tags.tag_id
+		WHERE note_tags.note_id = notes.id), '') as tags`
+
+const noteSelect = `notes.id, notes.title, notes.body, ` + noteColumns
+
+var noteListSelect = fmt.Sprintf(`notes.id, notes.title, LEFT(notes.body, %d) as body,
+	char_length(notes.body) > %d as body_truncated, `, NoteListBodyLength, NoteListBodyLength) + noteColumns
+
+func noteTagFilter(filter helper.TagFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(filter.Names) == 0 {
+			return db
+		}
+
+		matches := `(SELECT COUNT(*) FROM note_tags INNER JOIN tags ON tags.id = note_tags.tag_id
+			WHERE note_tags.note_id = notes.id AND tags.name IN ?)`
+		if filter.All {
+			return db.Where(matches+" = ?", filter.Names, len(filter.Names))
+		}
+		return db.Where(matches+" > 0", filter.Names)
+	}
+}
+
+func noteCategoryTreeFilter(userID int, categoryID int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if categoryID == 0 {
+			return db
+		}
+		return db.Where("notes.category_id IN ("+categorySubtree+")", categoryID, userID)
+	}
+}
+
+func noteSortValue(note domain.ScanNote, field string) interface{} {
+	switch field {
+	case "title":
+		return note.Title
+	case "category_id":
+		return note.CategoryID
+	case "created_at":
+		return note.CreatedAt
+	case "updated_at":
+		return note.UpdatedAt
+	case "deleted_at":
+		return *note.DeletedAt
+	default:
+		return note.ID
+	}
 }
 
 type noteRepositoryImpl struct {
@@ -21,56 +145,307 @@ func NewNoteRepositoryImpl() *noteRepositoryImpl {
 	return &noteRepositoryImpl{}
 }
 
-func (r *noteRepositoryImpl) FindAll(tx *gorm.DB, page int, pageSize int) ([]domain.ScanNote, error) {
+func (r *noteRepositoryImpl) FindAll(ctx context.Context, tx *gorm.DB, userID int,
+	spec helper.QuerySpec) ([]domain.ScanNote, helper.PageInfo, error) {
+	tx = tx.WithContext(ctx)
+	return r.findPage(func() *gorm.DB {
+		return tx.Model(&domain.Note{}).
+			Joins("inner join categories on categories.id = notes.category_id").
+			Where("notes.user_id = ?", userID).
+			Scopes(helper.Filter(spec.Filters, NoteQueryFields), noteTagFilter(spec.Tags),
+				noteCategoryTreeFilter(userID, spec.CategoryTree))
+	}, spec, NoteQueryFields)
+}
+
+func (r *noteRepositoryImpl) FindTrash(ctx context.Context, tx *gorm.DB, userID int,
+	spec helper.QuerySpec) ([]domain.ScanNote, helper.PageInfo, error) {
+	tx = tx.WithContext(ctx)
+	return r.findPage(func() *gorm.DB {
+		return tx.Unscoped().Model(&domain.Note{}).
+			Joins("inner join categories on categories.id = notes.category_id").
+			Where("notes.user_id = ? AND notes.deleted_at IS NOT NULL", userID).
+			Scopes(helper.Filter(spec.Filters, NoteTrashQueryFields), noteTagFilter(spec.Tags),
+				noteCategoryTreeFilter(userID, spec.CategoryTree))
+	}, spec, NoteTrashQueryFields)
+}
+
+func (r *noteRepositoryImpl) findPage(query func() *gorm.DB, spec helper.QuerySpec,
+	fields helper.QueryFields) ([]domain.ScanNote, helper.PageInfo, error) {
 	var note []domain.ScanNote
-	if page > 0 && pageSize > 0 {
-		tx = tx.Scopes(helper.Paginate(page, pageSize))
+	var info helper.PageInfo
+
+	if spec.Keyset {
+		if err := query().
+			Scopes(helper.Keyset(spec.Sorts, fields, spec.After, spec.PageSize)).
+			Select(noteListSelect).
+			Scan(&note).Error; err != nil {
+			return note, info, translateError(err)
+		}
+		if len(note) > spec.PageSize {
+			note = note[:spec.PageSize]
+			last := note[len(note)-1]
+			info.Next = helper.NewCursor(spec.Sorts, fields, func(field string) interface{} {
+				return noteSortValue(last, field)
+			})
+		}
+		return note, info, nil
 	}
-	if err := tx.Model(&domain.Note{}).
-		Order("notes.id asc").
-		Select("notes.id, notes.title, notes.body, categories.name as category").
-		Joins("inner join categories on categories.id = notes.category_id").
+
+	if err := query().Count(&info.TotalItems).Error; err != nil {
+		return note, info, translateError(err)
+	}
+	if err := query().
+		Scopes(helper.Sort(spec.Sorts, fields), helper.Paginate(spec.Page, spec.PageSize)).
+		Select(noteListSelect).
 		Scan(&note).Error; err != nil {
-		return note, err
+		return note, info, translateError(err)
 	}
 
-	return note, nil
+	return note, info, nil
+}
+
+func (r *noteRepositoryImpl) Search(ctx context.Context, tx *gorm.DB, userID int,
+	keyword string, spec helper.QuerySpec) ([]domain.ScanNote, helper.PageInfo, error) {
+	tx = tx.WithContext(ctx)
+	var note []domain.ScanNote
+	var info helper.PageInfo
+	query := func() *gorm.DB {
+		return tx.Model(&domain.Note{}).
+			Joins("inner join categories on categories.id = notes.category_id").
+			Where("notes.user_id = ?", userID).
+			Where(noteSearchDocument+" @@ websearch_to_tsquery('english', ?)", keyword).
+			Scopes(helper.Filter(spec.Filters, NoteQueryFields), noteTagFilter(spec.Tags),
+				noteCategoryTreeFilter(userID, spec.CategoryTree))
+	}
+
+	if err := query().Count(&info.TotalItems).Error; err != nil {
+		return note, info, translateError(err)
+	}
+	rankQuery := query()
+	if len(spec.Sorts) == 0 {
+		rankQuery = rankQuery.Order("rank desc")
+	}
+	if err := rankQuery.
+		Scopes(helper.Sort(spec.Sorts, NoteQueryFields), helper.Paginate(spec.Page, spec.PageSize)).
+		Select(noteListSelect+`,
+			ts_rank(`+noteSearchDocument+`, websearch_to_tsquery('english', ?)) as rank,
+			ts_headline('english', translate(notes.title || ' ' || notes.body, chr(1) || chr(2), ''),
+				websearch_to_tsquery('english', ?), ?) as snippet`, keyword, keyword, snippetOptions).
+		Scan(&note).Error; err != nil {
+		return note, info, translateError(err)
+	}
+	for i := range note {
+		note[i].Snippet = markSnippet(note[i].Snippet)
+	}
+
+	return note, info, nil
 }
 
-func (r *noteRepositoryImpl) IsExistById(tx *gorm.DB, id int) bool {
+func (r *noteRepositoryImpl) IsExistById(ctx context.Context, tx *gorm.DB, userID int, id int) bool {
+	tx = tx.WithContext(ctx)
 	var count int64
-	if tx.Model(&domain.Note{}).Where("id = ?", id).Count(&count); count == 0 {
+	if tx.Model(&domain.Note{}).Where("id = ? AND user_id = ?", id, userID).Count(&count); count == 0 {
 		return false
 	}
 
 	return true
 }
 
-func (r *noteRepositoryImpl) FindById(tx *gorm.DB, id int) (domain.ScanNote, error) {
+func (r *noteRepositoryImpl) FindById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.ScanNote, error) {
+	tx = tx.WithContext(ctx)
 	var note domain.ScanNote
 	if err := tx.Model(&domain.Note{}).
-		Select("notes.id, notes.title, notes.body, categories.name as category").
-		Where("notes.id = ?", id).
+		Select(noteSelect).
+		Where("notes.id = ? AND notes.user_id = ?", id, userID).
 		Joins("inner join categories on categories.id = notes.category_id").
 		Scan(&note).Error; err != nil {
-		return note, err
+		return note, translateError(err)
+	}
+
+	return note, nil
+}
+
+// Save inserts a new note. An existing note is only written when its stored
+// version still equals note.Version, and the version is bumped by one.
+func (r *noteRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, note domain.Note) (domain.Note, error) {
+	tx = tx.WithContext(ctx)
+	if note.ID == 0 {
+		note.Version = 1
+		if err := tx.Create(&note).Error; err != nil {
+			return note, translateError(err)
+		}
+		return note, nil
+	}
+
+	version := note.Version
+	note.Version++
+	result := tx.Model(&note).Where("version = ?", version).
+		Select("*").Omit("Category", "User").Updates(&note)
+	if result.Error != nil {
+		return note, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return note, ErrStaleVersion
 	}
 
 	return note, nil
 }
 
-func (r *noteRepositoryImpl) Save(tx *gorm.DB, note domain.Note) (domain.Note, error) {
-	if err := tx.Save(&note).Error; err != nil {
-		return note, err
+// CreateAll inserts new notes in batches and fills in their ids.
+func (r *noteRepositoryImpl) CreateAll(ctx context.Context, tx *gorm.DB, notes []domain.Note) ([]domain.Note, error) {
+	tx = tx.WithContext(ctx)
+	for i := range notes {
+		notes[i].Version = 1
+	}
+	if err := tx.Omit("Category", "User").CreateInBatches(&notes, noteBatchSize).Error; err != nil {
+		return notes, translateError(err)
+	}
+
+	return notes, nil
+}
+
+func (r *noteRepositoryImpl) AddTags(ctx context.Context, tx *gorm.DB, noteTags []domain.NoteTag) error {
+	tx = tx.WithContext(ctx)
+	if len(noteTags) == 0 {
+		return nil
+	}
+	return translateError(tx.Omit("Note", "Tag").CreateInBatches(&noteTags, noteBatchSize).Error)
+}
+
+func (r *noteRepositoryImpl) Delete(ctx context.Context, tx *gorm.DB, userID int, id int, version int) error {
+	tx = tx.WithContext(ctx)
+	result := tx.Where("id = ? AND user_id = ? AND version = ?", id, userID, version).Delete(&domain.Note{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaleVersion
+	}
+
+	return nil
+}
+
+// DeleteByCategories moves every note of the categories to the trash without
+// looking at versions.
+func (r *noteRepositoryImpl) DeleteByCategories(ctx context.Context, tx *gorm.DB, userID int, categoryIDs []int) (int64, error) {
+	tx = tx.WithContext(ctx)
+	result := tx.Where("category_id IN ? AND user_id = ?", categoryIDs, userID).Delete(&domain.Note{})
+	return result.RowsAffected, translateError(result.Error)
+}
+
+func (r *noteRepositoryImpl) MoveToCategory(ctx context.Context, tx *gorm.DB, userID int, fromID int, toID int) (int64, error) {
+	tx = tx.WithContext(ctx)
+	result := tx.Model(&domain.Note{}).
+		Where("category_id = ? AND user_id = ?", fromID, userID).
+		Updates(map[string]interface{}{"category_id": toID, "version": gorm.Expr("version + 1")})
+	return result.RowsAffected, translateError(result.Error)
+}
+
+func (r *noteRepositoryImpl) FindTrashedById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.Note, error) {
+	tx = tx.WithContext(ctx)
+	var note domain.Note
+	if err := tx.Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		First(&note).Error; err != nil {
+		return note, translateError(err)
 	}
 
 	return note, nil
 }
 
-func (r *noteRepositoryImpl) Delete(tx *gorm.DB, id int) error {
-	if err := tx.Where("id = ?", id).Delete(&domain.Note{}).Error; err != nil {
-		return err
+func (r *noteRepositoryImpl) Restore(ctx context.Context, tx *gorm.DB, userID int, id int) error {
+	tx = tx.WithContext(ctx)
+	if err := tx.Unscoped().Model(&domain.Note{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("deleted_at", nil).Error; err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
+
+func (r *noteRepositoryImpl) Purge(ctx context.Context, tx *gorm.DB, userID int, id int) error {
+	tx = tx.WithContext(ctx)
+	if err := tx.Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Delete(&domain.Note{}).Error; err != nil {
+		return translateError(err)
 	}
 
 	return nil
 }
+
+func (r *noteRepositoryImpl) PurgeTrashed(ctx context.Context, tx *gorm.DB, before time.Time) (int64, error) {
+	tx = tx.WithContext(ctx)
+	result := tx.Unscoped().Where("deleted_at < ?", before).Delete(&domain.Note{})
+	return result.RowsAffected, translateError(result.Error)
+}
+
+func (r *noteRepositoryImpl) ReplaceTags(ctx context.Context, tx *gorm.DB, noteID int, tagIDs []int) error {
+	tx = tx.WithContext(ctx)
+	if err := tx.Where("note_id = ?", noteID).Delete(&domain.NoteTag{}).Error; err != nil {
+		return translateError(err)
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	noteTags := make([]domain.NoteTag, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		noteTags = append(noteTags, domain.NoteTag{NoteID: noteID, TagID: tagID})
+	}
+	if err := tx.Omit("Note", "Tag").Create(&noteTags).Error; err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
+
+// StreamAll calls fn for every live note of the user, ordered by category and
+// id, reading the rows one by one instead of loading them all.
+func (r *noteRepositoryImpl) StreamAll(ctx context.Context, tx *gorm.DB, userID int, fn func(note domain.ScanNote) error) error {
+	tx = tx.WithContext(ctx)
+	rows, err := tx.Model(&domain.Note{}).
+		Select(noteSelect).
+		Joins("inner join categories on categories.id = notes.category_id").
+		Where("notes.user_id = ?", userID).
+		Order("notes.category_id, notes.id").
+		Rows()
+	if err != nil {
+		return translateError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var note domain.ScanNote
+		if err := tx.ScanRows(rows, &note); err != nil {
+			return translateError(err)
+		}
+		if err := fn(note); err != nil {
+			return err
+		}
+	}
+
+	return translateError(rows.Err())
+}
+
+// FindExisting looks up notes by id across all users, including the ones in
+// the trash, so an import can tell which ids are taken.
+func (r *noteRepositoryImpl) FindExisting(ctx context.Context, tx *gorm.DB, ids []int) ([]domain.Note, error) {
+	tx = tx.WithContext(ctx)
+	var notes []domain.Note
+	if len(ids) == 0 {
+		return notes, nil
+	}
+	if err := tx.Unscoped().Where("id IN ?", ids).Find(&notes).Error; err != nil {
+		return notes, translateError(err)
+	}
+
+	return notes, nil
+}
+
+// SyncIdSequence moves the id sequence past notes inserted with their own id.
+func (r *noteRepositoryImpl) SyncIdSequence(ctx context.Context, tx *gorm.DB) error {
+	tx = tx.WithContext(ctx)
+	return translateError(tx.Exec("SELECT setval(pg_get_serial_sequence('notes', 'id'), COALESCE((SELECT MAX(id) FROM notes), 1))").Error)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"gorm.io/gorm"
+)
+
+type NoteRevisionRepository interface {
+	FindAll(ctx context.Context, tx *gorm.DB, noteID int) ([]domain.NoteRevision, error)
+	FindByRevision(ctx context.Context, tx *gorm.DB, noteID int, revision int) (domain.NoteRevision, error)
+	IsExistByNoteId(ctx context.Context, tx *gorm.DB, noteID int) bool
+	Save(ctx context.Context, tx *gorm.DB, revision domain.NoteRevision) (domain.NoteRevision, error)
+	CreateFirst(ctx context.Context, tx *gorm.DB, revisions []domain.NoteRevision) error
+}
+
+type noteRevisionRepositoryImpl struct {
+}
+
+func NewNoteRevisionRepository() *noteRevisionRepositoryImpl {
+	return &noteRevisionRepositoryImpl{}
+}
+
+func (r *noteRevisionRepositoryImpl) FindAll(ctx context.Context, tx *gorm.DB, noteID int) ([]domain.NoteRevision, error) {
+	tx = tx.WithContext(ctx)
+	var revisions []domain.NoteRevision
+	if err := tx.Where("note_id = ?", noteID).Order("revision desc").Find(&revisions).Error; err != nil {
+		return revisions, translateError(err)
+	}
+
+	return revisions, nil
+}
+
+func (r *noteRevisionRepositoryImpl) FindByRevision(ctx context.Context, tx *gorm.DB, noteID int, revision int) (domain.NoteRevision, error) {
+	tx = tx.WithContext(ctx)
+	var noteRevision domain.NoteRevision
+	if err := tx.Where("note_id = ? AND revision = ?", noteID, revision).First(&noteRevision).Error; err != nil {
+		return noteRevision, translateError(err)
+	}
+
+	return noteRevision, nil
+}
+
+func (r *noteRevisionRepositoryImpl) IsExistByNoteId(ctx context.Context, tx *gorm.DB, noteID int) bool {
+	tx = tx.WithContext(ctx)
+	var count int64
+	if tx.Model(&domain.NoteRevision{}).Where("note_id = ?", noteID).Count(&count); count == 0 {
+		return false
+	}
+
+	return true
+}
+
+// Save numbers the revision after the latest one of the same note. Two writers
+// racing for the same number are stopped by the unique index.
+func (r *noteRevisionRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, revision domain.NoteRevision) (domain.NoteRevision, error) {
+	tx = tx.WithContext(ctx)
+	if err := tx.Model(&domain.NoteRevision{}).
+		Where("note_id = ?", revision.NoteID).
+		Select("COALESCE(MAX(revision), 0) + 1").
+		Scan(&revision.Revision).Error; err != nil {
+		return revision, translateError(err)
+	}
+	if err := tx.Omit("Note").Create(&revision).Error; err != nil {
+		return revision, translateError(err)
+	}
+
+	return revision, nil
+}
+
+// CreateFirst stores the first revision of many new notes at once.
+func (r *noteRevisionRepositoryImpl) CreateFirst(ctx context.Context, tx *gorm.DB, revisions []domain.NoteRevision) error {
+	tx = tx.WithContext(ctx)
+	if len(revisions) == 0 {
+		return nil
+	}
+	for i := range revisions {
+		revisions[i].Revision = 1
+	}
+	return translateError(tx.Omit("Note").CreateInBatches(&revisions, noteBatchSize).Error)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagRepository interface {
+	FindAll(ctx context.Context, tx *gorm.DB, userID int) ([]domain.Tag, error)
+	FindById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.Tag, error)
+	FindOrCreate(ctx context.Context, tx *gorm.DB, userID int, names []string) ([]domain.Tag, error)
+	IsExistByName(ctx context.Context, tx *gorm.DB, userID int, name string) bool
+	Save(ctx context.Context, tx *gorm.DB, tag domain.Tag) (domain.Tag, error)
+	Delete(ctx context.Context, tx *gorm.DB, userID int, id int) error
+}
+
+type tagRepositoryImpl struct {
+}
+
+func NewTagRepository() *tagRepositoryImpl {
+	return &tagRepositoryImpl{}
+}
+
+func (r *tagRepositoryImpl) FindAll(ctx context.Context, tx *gorm.DB, userID int) ([]domain.Tag, error) {
+	tx = tx.WithContext(ctx)
+	var tags []domain.Tag
+	if err := tx.Where("user_id = ?", userID).Order("name asc").Find(&tags).Error; err != nil {
+		return tags, translateError(err)
+	}
+
+	return tags, nil
+}
+
+func (r *tagRepositoryImpl) FindById(ctx context.Context, tx *gorm.DB, userID int, id int) (domain.Tag, error) {
+	tx = tx.WithContext(ctx)
+	var tag domain.Tag
+	if err := tx.Where("user_id = ?", userID).First(&tag, id).Error; err != nil {
+		return tag, translateError(err)
+	}
+
+	return tag, nil
+}
+
+// FindOrCreate returns the user's tags with the given names, creating the
+// ones that don't exist yet. Names must already be normalized.
+func (r *tagRepositoryImpl) FindOrCreate(ctx context.Context, tx *gorm.DB, userID int, names []string) ([]domain.Tag, error) {
+	tx = tx.WithContext(ctx)
+	var tags []domain.Tag
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	newTags := make([]domain.Tag, 0, len(names))
+	for _, name := range names {
+		newTags = append(newTags, domain.Tag{Name: name, UserID: userID})
+	}
+	if err := tx.Omit("User").Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error; err != nil {
+		return tags, translateError(err)
+	}
+
+	if err := tx.Where("user_id = ? AND name IN ?", userID, names).Order("name asc").Find(&tags).Error; err != nil {
+		return tags, translateError(err)
+	}
+
+	return tags, nil
+}
+
+func (r *tagRepositoryImpl) IsExistByName(ctx context.Context, tx *gorm.DB, userID int, name string) bool {
+	tx = tx.WithContext(ctx)
+	var count int64
+	if tx.Model(&domain.Tag{}).Where("user_id = ? AND name = ?", userID, name).Count(&count); count == 0 {
+		return false
+	}
+
+	return true
+}
+
+func (r *tagRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, tag domain.Tag) (domain.Tag, error) {
+	tx = tx.WithContext(ctx)
+	if err := tx.Omit("User").Save(&tag).Error; err != nil {
+		return tag, translateError(err)
+	}
+
+	return tag, nil
+}
+
+func (r *tagRepositoryImpl) Delete(ctx context.Context, tx *gorm.DB, userID int, id int) error {
+	tx = tx.WithContext(ctx)
+	if err := tx.Where("user_id = ?", userID).Delete(&domain.Tag{}, id).Error; err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	IsExistByEmail(ctx context.Context, tx *gorm.DB, email string) bool
+	FindByEmail(ctx context.Context, tx *gorm.DB, email string) (domain.User, error)
+	FindById(ctx context.Context, tx *gorm.DB, id int) (domain.User, error)
+	Save(ctx context.Context, tx *gorm.DB, user domain.User) (domain.User, error)
+}
+
+type userRepositoryImpl struct {
+}
+
+func NewUserRepository() *userRepositoryImpl {
+	return &userRepositoryImpl{}
+}
+
+func (r *userRepositoryImpl) IsExistByEmail(ctx context.Context, tx *gorm.DB, email string) bool {
+	tx = tx.WithContext(ctx)
+	var count int64
+	if tx.Model(&domain.User{}).Where("email = ?", email).Count(&count); count == 0 {
+		return false
+	}
+
+	return true
+}
+
+func (r *userRepositoryImpl) FindByEmail(ctx context.Context, tx *gorm.DB, email string) (domain.User, error) {
+	tx = tx.WithContext(ctx)
+	var user domain.User
+	if err := tx.Where("email = ?", email).First(&user).Error; err != nil {
+		return user, translateError(err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepositoryImpl) FindById(ctx context.Context, tx *gorm.DB, id int) (domain.User, error) {
+	tx = tx.WithContext(ctx)
+	var user domain.User
+	if err := tx.First(&user, id).Error; err != nil {
+		return user, translateError(err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, user domain.User) (domain.User, error) {
+	tx = tx.WithContext(ctx)
+	if err := tx.Save(&user).Error; err != nil {
+		return user, translateError(err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	APIKeyPrefix = "ecn_"
+
+	apiKeyDisplayLength = 12
+	apiKeyTouchInterval = time.Minute
+)
+
+type APIKeyService interface {
+	GetAll(ctx context.Context, userID int) ([]web.APIKeyResponse, error)
+	Create(ctx context.Context, userID int, request web.APIKeyRequest) (web.APIKeyResponse, error)
+	Rotate(ctx context.Context, userID int, id int) (web.APIKeyResponse, error)
+	Revoke(ctx context.Context, userID int, id int) error
+	Authenticate(ctx context.Context, key string) (domain.APIKey, error)
+}
+
+type apiKeyServiceImpl struct {
+	TxManager  TxManager
+	Validate   *validator.Validate
+	Repository repository.APIKeyRepository
+}
+
+func NewAPIKeyService(txManager TxManager, validate *validator.Validate, repository repository.APIKeyRepository) *apiKeyServiceImpl {
+	return &apiKeyServiceImpl{
+		TxManager:  txManager,
+		Validate:   validate,
+		Repository: repository,
+	}
+}
+
+func newAPIKeyResponse(apiKey domain.APIKey) web.APIKeyResponse {
+	return web.APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     strings.Split(apiKey.Scopes, ","),
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+// generateAPIKey returns the plaintext key, which is only ever shown to the
+// client once, together with the SHA-256 hash that gets stored.
+func generateAPIKey() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *apiKeyServiceImpl) GetAll(ctx context.Context, userID int) ([]web.APIKeyResponse, error) {
+	var apiKeys []web.APIKeyResponse
+
+	var apiKeysDom []domain.APIKey
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		apiKeysDom, err = s.Repository.FindAll(ctx, tx, userID)
+		return err
+	})
+	if errFind != nil {
+		return apiKeys, errFind
+	}
+
+	for _, kDom := range apiKeysDom {
+		apiKeys = append(apiKeys, newAPIKeyResponse(kDom))
+	}
+
+	return apiKeys, nil
+}
+
+func (s *apiKeyServiceImpl) Create(ctx context.Context, userID int, request web.APIKeyRequest) (web.APIKeyResponse, error) {
+	var apiKey web.APIKeyResponse
+	if errValidate := s.Validate.Struct(request); errValidate != nil {
+		return apiKey, errValidate
+	}
+
+	key, keyHash, errGenerate := generateAPIKey()
+	if errGenerate != nil {
+		return apiKey, errGenerate
+	}
+
+	var apiKeyDom domain.APIKey
+	errSave := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		var err error
+		apiKeyDom, err = s.Repository.Save(ctx, tx, domain.APIKey{
+			Name:    request.Name,
+			Prefix:  key[:apiKeyDisplayLength],
+			KeyHash: keyHash,
+			Scopes:  strings.Join(request.Scopes, ","),
+			UserID:  userID,
+		})
+		return err
+	})
+	if errSave != nil {
+		return apiKey, errSave
+	}
+
+	apiKey = newAPIKeyResponse(apiKeyDom)
+	apiKey.Key = key
+	return apiKey, nil
+}
+
+func (s *apiKeyServiceImpl) Rotate(ctx context.Context, userID int, id int) (web.APIKeyResponse, error) {
+	var apiKey web.APIKeyResponse
+
+	key, keyHash, errGenerate := generateAPIKey()
+	if errGenerate != nil {
+		return apiKey, errGenerate
+	}
+
+	var apiKeyDom domain.APIKey
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		var errFind error
+		apiKeyDom, errFind = s.Repository.FindById(ctx, tx, userID, id)
+		if errFind != nil || apiKeyDom.RevokedAt != nil {
+			return &exception.NotFoundError{Entity: "api key"}
+		}
+
+		apiKeyDom.Prefix = key[:apiKeyDisplayLength]
+		apiKeyDom.KeyHash = keyHash
+		apiKeyDom.LastUsedAt = nil
+		var errSave error
+		apiKeyDom, errSave = s.Repository.Save(ctx, tx, apiKeyDom)
+		return errSave
+	})
+	if errTx != nil {
+		return apiKey, errTx
+	}
+
+	apiKey = newAPIKeyResponse(apiKeyDom)
+	apiKey.Key = key
+	return apiKey, nil
+}
+
+func (s *apiKeyServiceImpl) Revoke(ctx context.Context, userID int, id int) error {
+	return s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		apiKeyDom, errFind := s.Repository.FindById(ctx, tx, userID, id)
+		if errFind != nil || apiKeyDom.RevokedAt != nil {
+			return &exception.NotFoundError{Entity: "api key"}
+		}
+
+		revokedAt := time.Now()
+		apiKeyDom.RevokedAt = &revokedAt
+		_, errSave := s.Repository.Save(ctx, tx, apiKeyDom)
+		return errSave
+	})
+}
+
+func (s *apiKeyServiceImpl) Authenticate(ctx context.Context, key string) (domain.APIKey, error) {
+	var apiKeyDom domain.APIKey
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		var errFind error
+		apiKeyDom, errFind = s.Repository.FindByHash(ctx, tx, hashAPIKey(key))
+		if errFind != nil || apiKeyDom.RevokedAt != nil {
+			return &exception.UnauthorizedError{Message: "api key is invalid or revoked"}
+		}
+
+		now := time.Now()
+		if apiKeyDom.LastUsedAt == nil || now.Sub(*apiKeyDom.LastUsedAt) > apiKeyTouchInterval {
+			return s.Repository.TouchLastUsed(ctx, tx, apiKeyDom.ID, now)
+		}
+		return nil
+	})
+	if errTx != nil {
+		return apiKeyDom, errTx
+	}
+
+	return apiKeyDom, nil
+}
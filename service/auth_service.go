@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// dummyPasswordHash is compared against when the email is unknown, so a failed
+// login takes the same time whether or not the account exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+type AuthService interface {
+	Register(ctx context.Context, request web.RegisterRequest) (web.UserResponse, error)
+	CreateUser(ctx context.Context, request web.CreateUserRequest) (web.UserResponse, error)
+	Login(ctx context.Context, request web.LoginRequest) (web.TokenResponse, error)
+	Refresh(ctx context.Context, request web.RefreshRequest) (web.TokenResponse, error)
+}
+
+type authServiceImpl struct {
+	TxManager      TxManager
+	Validate       *validator.Validate
+	UserRepository repository.UserRepository
+	Tokens         *helper.TokenManager
+}
+
+func NewAuthService(txManager TxManager, validate *validator.Validate, userRepository repository.UserRepository,
+	tokens *helper.TokenManager) *authServiceImpl {
+	return &authServiceImpl{
+		TxManager:      txManager,
+		Validate:       validate,
+		UserRepository: userRepository,
+		Tokens:         tokens,
+	}
+}
+
+func (s *authServiceImpl) Register(ctx context.Context, request web.RegisterRequest) (web.UserResponse, error) {
+	request.Email = strings.ToLower(strings.TrimSpace(request.Email))
+	if errValidate := s.Validate.Struct(request); errValidate != nil {
+		return web.UserResponse{}, errValidate
+	}
+
+	return s.createUser(ctx, request.Email, request.Password, domain.RoleEditor)
+}
+
+func (s *authServiceImpl) CreateUser(ctx context.Context, request web.CreateUserRequest) (web.UserResponse, error) {
+	request.Email = strings.ToLower(strings.TrimSpace(request.Email))
+	if errValidate := s.Validate.Struct(request); errValidate != nil {
+		return web.UserResponse{}, errValidate
+	}
+
+	return s.createUser(ctx, request.Email, request.Password, request.Role)
+}
+
+func (s *authServiceImpl) createUser(ctx context.Context, email string, password string, role string) (web.UserResponse, error) {
+	var userResponse web.UserResponse
+	hash, errHash := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errHash != nil {
+		return userResponse, errHash
+	}
+
+	var userDom domain.User
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		if isExist := s.UserRepository.IsExistByEmail(ctx, tx, email); isExist {
+			return &exception.BadRequestError{Message: "email already registered"}
+		}
+
+		var errSave error
+		userDom, errSave = s.UserRepository.Save(ctx, tx, domain.User{
+			Email:    email,
+			Password: string(hash),
+			Role:     role,
+		})
+		return errSave
+	})
+	if errTx != nil {
+		return userResponse, errTx
+	}
+
+	userResponse = web.UserResponse{
+		ID:        userDom.ID,
+		Email:     userDom.Email,
+		Role:      userDom.Role,
+		CreatedAt: userDom.CreatedAt,
+	}
+	return userResponse, nil
+}
+
+func (s *authServiceImpl) Login(ctx context.Context, request web.LoginRequest) (web.TokenResponse, error) {
+	var tokens web.TokenResponse
+	if errValidate := s.Validate.Struct(request); errValidate != nil {
+		return tokens, errValidate
+	}
+
+	var userDom domain.User
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		userDom, err = s.UserRepository.FindByEmail(ctx, tx, strings.ToLower(strings.TrimSpace(request.Email)))
+		return err
+	})
+	if errFind != nil {
+		if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return tokens, errFind
+		}
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(request.Password))
+		return tokens, &exception.UnauthorizedError{Message: "invalid email or password"}
+	}
+
+	if errCompare := bcrypt.CompareHashAndPassword([]byte(userDom.Password), []byte(request.Password)); errCompare != nil {
+		return tokens, &exception.UnauthorizedError{Message: "invalid email or password"}
+	}
+
+	return s.Tokens.Generate(userDom.ID, userDom.Role)
+}
+
+func (s *authServiceImpl) Refresh(ctx context.Context, request web.RefreshRequest) (web.TokenResponse, error) {
+	var tokens web.TokenResponse
+	if errValidate := s.Validate.Struct(request); errValidate != nil {
+		return tokens, errValidate
+	}
+
+	claims, errParse := s.Tokens.Parse(request.RefreshToken, helper.RefreshToken)
+	if errParse != nil {
+		return tokens, &exception.UnauthorizedError{Message: errParse.Error()}
+	}
+
+	var userDom domain.User
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		userDom, err = s.UserRepository.FindById(ctx, tx, claims.UserID)
+		if err != nil {
+			return &exception.UnauthorizedError{Message: "user no longer exists"}
+		}
+		return nil
+	})
+	if errFind != nil {
+		return tokens, errFind
+	}
+
+	return s.Tokens.Generate(userDom.ID, userDom.Role)
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
+	"slices"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
 	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"github.com/naomigrain/echo-crud-notes/model/web"
 	"github.com/naomigrain/echo-crud-notes/repository"
@@ -10,136 +14,391 @@ import (
 )
 
 type CategoryService interface {
-	Create(category web.CategoryJSON) (web.CategoryJSON, error)
-	GetById(id int) (web.CategoryJSON, error)
-	GetAll(page int, pageSize int) ([]web.CategoryJSON, error)
-	Update(category web.CategoryJSON) (web.CategoryJSON, error)
-	Delete(id int) error
+	Create(ctx context.Context, userID int, category web.CategoryJSON) (web.CategoryJSON, error)
+	GetById(ctx context.Context, userID int, id int) (web.CategoryJSON, error)
+	GetAll(ctx context.Context, userID int, spec helper.QuerySpec) ([]web.CategoryJSON, helper.PageInfo, error)
+	Update(ctx context.Context, userID int, category web.CategoryJSON) (web.CategoryJSON, error)
+	Delete(ctx context.Context, userID int, request web.CategoryDeleteRequest) (web.CategoryDeleteResponse, error)
+	DeleteAny(ctx context.Context, request web.CategoryDeleteRequest) (web.CategoryDeleteResponse, error)
+	GetTree(ctx context.Context, userID int) ([]web.CategoryTreeResponse, error)
+	GetDescendants(ctx context.Context, userID int, id int) ([]web.CategoryJSON, error)
 }
 
 type categoryServiceImpl struct {
-	DB         *gorm.DB
-	Validate   *validator.Validate
-	Repository repository.CategoryRepository
+	TxManager      TxManager
+	Validate       *validator.Validate
+	Repository     repository.CategoryRepository
+	NoteRepository repository.NoteRepository
 }
 
-func NewCategoryService(db *gorm.DB, validate *validator.Validate, repository repository.CategoryRepository) *categoryServiceImpl {
+func NewCategoryService(txManager TxManager, validate *validator.Validate, repository repository.CategoryRepository,
+	noteRepository repository.NoteRepository) *categoryServiceImpl {
 	return &categoryServiceImpl{
-		DB:         db,
-		Validate:   validate,
-		Repository: repository,
+		TxManager:      txManager,
+		Validate:       validate,
+		Repository:     repository,
+		NoteRepository: noteRepository,
+	}
+}
+
+func newCategoryResponse(category domain.Category) web.CategoryJSON {
+	return web.CategoryJSON{
+		ID:        category.ID,
+		Name:      category.Name,
+		ParentID:  category.ParentID,
+		Version:   category.Version,
+		CreatedAt: category.CreatedAt,
+		UpdatedAt: category.UpdatedAt,
 	}
 }
 
-func (s *categoryServiceImpl) GetAll(page int, pageSize int) ([]web.CategoryJSON, error) {
+// newCategoryTree nests the categories under their parents. A category whose
+// parent is missing, for example because it is in the trash, becomes a root.
+func newCategoryTree(categories []domain.Category) []web.CategoryTreeResponse {
+	ids := make(map[int]bool, len(categories))
+	for _, cDom := range categories {
+		ids[cDom.ID] = true
+	}
+
+	children := make(map[int][]domain.Category)
+	for _, cDom := range categories {
+		parentID := 0
+		if cDom.ParentID != nil && ids[*cDom.ParentID] {
+			parentID = *cDom.ParentID
+		}
+		children[parentID] = append(children[parentID], cDom)
+	}
+
+	var build func(parentID int) []web.CategoryTreeResponse
+	build = func(parentID int) []web.CategoryTreeResponse {
+		nodes := []web.CategoryTreeResponse{}
+		for _, cDom := range children[parentID] {
+			nodes = append(nodes, web.CategoryTreeResponse{
+				ID:       cDom.ID,
+				Name:     cDom.Name,
+				ParentID: cDom.ParentID,
+				Version:  cDom.Version,
+				Children: build(cDom.ID),
+			})
+		}
+		return nodes
+	}
+
+	return build(0)
+}
+
+// checkParent makes sure the new parent of a category exists and does not lie
+// in the subtree of the category itself. id is 0 for a new category.
+func (s *categoryServiceImpl) checkParent(ctx context.Context, tx *gorm.DB, userID int, id int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == id {
+		return &exception.BadRequestError{Message: "category can not be its own parent"}
+	}
+	if isExist := s.Repository.IsExistById(ctx, tx, userID, *parentID); !isExist {
+		return &exception.BadRequestError{Message: "parent category does not exist"}
+	}
+	if id == 0 {
+		return nil
+	}
+
+	// Trashed categories count too, they can be restored below the new parent.
+	subtree, errFind := s.Repository.FindSubtreeIdsWithTrashed(ctx, tx, userID, id)
+	if errFind != nil {
+		return errFind
+	}
+	if slices.Contains(subtree, *parentID) {
+		return &exception.BadRequestError{Message: "category can not be moved below one of its descendants"}
+	}
+
+	return nil
+}
+
+func (s *categoryServiceImpl) GetAll(ctx context.Context, userID int, spec helper.QuerySpec) ([]web.CategoryJSON, helper.PageInfo, error) {
 	var categories []web.CategoryJSON
+	if errSpec := spec.Validate(repository.CategoryQueryFields); errSpec != nil {
+		return categories, helper.PageInfo{}, &exception.BadRequestError{Message: errSpec.Error()}
+	}
 
-	tx := s.DB.Begin()
-	categoriesDom, errFind := s.Repository.FindAll(tx, page, pageSize)
+	var categoriesDom []domain.Category
+	var info helper.PageInfo
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		categoriesDom, info, err = s.Repository.FindAll(ctx, tx, userID, spec)
+		return err
+	})
 	if errFind != nil {
-		return categories, errFind
+		return categories, info, errFind
 	}
 
 	for _, cDom := range categoriesDom {
-		categories = append(categories, web.CategoryJSON{
-			ID:   cDom.ID,
-			Name: cDom.Name,
-		})
+		categories = append(categories, newCategoryResponse(cDom))
 	}
 
-	return categories, nil
+	return categories, info, nil
 }
 
-func (s *categoryServiceImpl) GetById(id int) (web.CategoryJSON, error) {
+func (s *categoryServiceImpl) GetById(ctx context.Context, userID int, id int) (web.CategoryJSON, error) {
 	var category web.CategoryJSON
 
-	tx := s.DB.Begin()
-	categoryDom, errFind := s.Repository.FindById(tx, id)
+	var categoryDom domain.Category
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		categoryDom, err = s.Repository.FindById(ctx, tx, userID, id)
+		if err != nil {
+			return &exception.NotFoundError{Entity: "category"}
+		}
+		return nil
+	})
 	if errFind != nil {
-		return category, &exception.NotFoundError{Entity: "category"}
+		return category, errFind
 	}
 
-	category.ID = id
-	category.Name = categoryDom.Name
-	return category, nil
+	return newCategoryResponse(categoryDom), nil
 }
 
-func (s *categoryServiceImpl) Create(category web.CategoryJSON) (web.CategoryJSON, error) {
+func (s *categoryServiceImpl) Create(ctx context.Context, userID int, category web.CategoryJSON) (web.CategoryJSON, error) {
 	errValidate := s.Validate.Struct(category)
 	if errValidate != nil {
 		return category, errValidate
 	}
 
-	tx := s.DB.Begin()
-	categoryDom, errCreate := s.Repository.Save(tx, domain.Category{
-		Name: category.Name,
-	})
-	if errCreate != nil {
-		errRollback := tx.Rollback().Error
-		if errRollback != nil {
-			return category, errRollback
+	var categoryDom domain.Category
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		if errParent := s.checkParent(ctx, tx, userID, 0, category.ParentID); errParent != nil {
+			return errParent
 		}
-		return category, errCreate
-	}
 
-	if errCommit := tx.Commit().Error; errCommit != nil {
-		return category, errCommit
+		var errCreate error
+		categoryDom, errCreate = s.Repository.Save(ctx, tx, domain.Category{
+			Name:     category.Name,
+			ParentID: category.ParentID,
+			UserID:   userID,
+		})
+		return errCreate
+	})
+	if errTx != nil {
+		return category, errTx
 	}
 
 	category.ID = categoryDom.ID
+	category.Version = categoryDom.Version
+	category.CreatedAt = categoryDom.CreatedAt
+	category.UpdatedAt = categoryDom.UpdatedAt
 	return category, nil
 }
 
-func (s *categoryServiceImpl) Update(category web.CategoryJSON) (web.CategoryJSON, error) {
+func (s *categoryServiceImpl) Update(ctx context.Context, userID int, category web.CategoryJSON) (web.CategoryJSON, error) {
 	errValidate := s.Validate.Struct(category)
 	if errValidate != nil {
 		return category, errValidate
 	}
 
-	tx := s.DB.Begin()
-	_, errFind := s.Repository.FindById(tx, category.ID)
-	if errFind != nil {
-		return category, &exception.NotFoundError{Entity: "category"}
+	var categoryDom domain.Category
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		if errLock := s.Repository.LockTree(ctx, tx, userID); errLock != nil {
+			return errLock
+		}
+		var errFind error
+		categoryDom, errFind = s.Repository.FindById(ctx, tx, userID, category.ID)
+		if errFind != nil {
+			return &exception.NotFoundError{Entity: "category"}
+		}
+		if errVersion := checkVersion("category", category.Versions, categoryDom.Version); errVersion != nil {
+			return errVersion
+		}
+		if errParent := s.checkParent(ctx, tx, userID, category.ID, category.ParentID); errParent != nil {
+			return errParent
+		}
+
+		categoryDom.Name = category.Name
+		categoryDom.ParentID = category.ParentID
+		var errUpdate error
+		if categoryDom, errUpdate = s.Repository.Save(ctx, tx, categoryDom); errUpdate != nil {
+			return translateVersionError("category", errUpdate)
+		}
+		return nil
+	})
+	if errTx != nil {
+		return category, errTx
+	}
+
+	category.Version = categoryDom.Version
+	category.CreatedAt = categoryDom.CreatedAt
+	category.UpdatedAt = categoryDom.UpdatedAt
+	return category, nil
+}
+
+func (s *categoryServiceImpl) Delete(ctx context.Context, userID int, request web.CategoryDeleteRequest) (web.CategoryDeleteResponse, error) {
+	response := web.CategoryDeleteResponse{Strategy: request.Strategy}
+	if response.Strategy == "" {
+		response.Strategy = web.CategoryDeleteRestrict
 	}
 
-	_, errUpdate := s.Repository.Save(tx, domain.Category{
-		ID:   category.ID,
-		Name: category.Name,
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		if errLock := s.Repository.LockTree(ctx, tx, userID); errLock != nil {
+			return errLock
+		}
+		categoryDom, errFind := s.Repository.FindById(ctx, tx, userID, request.ID)
+		if errFind != nil {
+			return &exception.NotFoundError{Entity: "category"}
+		}
+		if errVersion := checkVersion("category", request.Versions, categoryDom.Version); errVersion != nil {
+			return errVersion
+		}
+
+		var errStrategy error
+		switch response.Strategy {
+		case web.CategoryDeleteRestrict:
+			errStrategy = s.restrict(ctx, tx, userID, request.ID)
+		case web.CategoryDeleteCascade:
+			response.Notes, response.Subcategories, errStrategy = s.cascade(ctx, tx, userID, request.ID)
+		case web.CategoryDeleteReassign:
+			response.Notes, response.Subcategories, errStrategy = s.reassign(ctx, tx, userID, request.ID, request.TargetID)
+		default:
+			errStrategy = &exception.BadRequestError{Message: "strategy is invalid"}
+		}
+		if errStrategy != nil {
+			return errStrategy
+		}
+
+		if errDel := s.Repository.Delete(ctx, tx, userID, request.ID, categoryDom.Version); errDel != nil {
+			return translateVersionError("category", errDel)
+		}
+		return nil
 	})
-	if errUpdate != nil {
-		errRollback := tx.Rollback().Error
-		if errRollback != nil {
-			return category, errRollback
+	if errTx != nil {
+		return response, errTx
+	}
+
+	return response, nil
+}
+
+// DeleteAny deletes a category whoever owns it, as admins may. The strategy
+// works on the tree and the notes of the owner.
+func (s *categoryServiceImpl) DeleteAny(ctx context.Context, request web.CategoryDeleteRequest) (web.CategoryDeleteResponse, error) {
+	var ownerID int
+	errTx := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var errFind error
+		if ownerID, errFind = s.Repository.FindOwnerId(ctx, tx, request.ID); errFind != nil {
+			return &exception.NotFoundError{Entity: "category"}
 		}
-		return category, errUpdate
+		return nil
+	})
+	if errTx != nil {
+		return web.CategoryDeleteResponse{}, errTx
 	}
 
-	if errCommit := tx.Commit().Error; errCommit != nil {
-		return category, errCommit
+	return s.Delete(ctx, ownerID, request)
+}
+
+// restrict refuses to delete a category that still has notes or subcategories.
+func (s *categoryServiceImpl) restrict(ctx context.Context, tx *gorm.DB, userID int, id int) error {
+	var usage web.CategoryUsage
+	var errCount error
+	if usage.Notes, errCount = s.Repository.CountNotes(ctx, tx, userID, id); errCount != nil {
+		return errCount
+	}
+	if usage.Subcategories, errCount = s.Repository.CountChildren(ctx, tx, userID, id); errCount != nil {
+		return errCount
 	}
 
-	return category, nil
+	if usage.Notes > 0 {
+		return &exception.ConflictError{Message: "category still has notes", Details: usage}
+	}
+	if usage.Subcategories > 0 {
+		return &exception.ConflictError{Message: "category still has subcategories", Details: usage}
+	}
+	return nil
 }
 
-func (s *categoryServiceImpl) Delete(id int) error {
-	tx := s.DB.Begin()
-	_, errFind := s.Repository.FindById(tx, id)
+// cascade moves every category below id, and the notes of id and of those
+// categories, to the trash. It returns how many notes and subcategories went.
+func (s *categoryServiceImpl) cascade(ctx context.Context, tx *gorm.DB, userID int, id int) (int64, int64, error) {
+	subtree, errFind := s.Repository.FindSubtreeIds(ctx, tx, userID, id)
 	if errFind != nil {
-		return &exception.NotFoundError{Entity: "category"}
+		return 0, 0, errFind
 	}
 
-	errDel := s.Repository.Delete(tx, id)
-	if errDel != nil {
-		errRollback := tx.Rollback().Error
-		if errRollback != nil {
-			return errRollback
+	notes, errNotes := s.NoteRepository.DeleteByCategories(ctx, tx, userID, subtree)
+	if errNotes != nil {
+		return 0, 0, errNotes
+	}
+	var subcategories int64
+	descendants := slices.DeleteFunc(subtree, func(categoryID int) bool { return categoryID == id })
+	if len(descendants) > 0 {
+		var errDel error
+		if subcategories, errDel = s.Repository.DeleteAll(ctx, tx, userID, descendants); errDel != nil {
+			return 0, 0, errDel
 		}
-		return errDel
 	}
 
-	if errCommit := tx.Commit().Error; errCommit != nil {
-		return errCommit
+	return notes, subcategories, nil
+}
+
+// reassign moves the notes and the direct subcategories of id to targetID,
+// which has to lie outside the subtree of id.
+func (s *categoryServiceImpl) reassign(ctx context.Context, tx *gorm.DB, userID int, id int, targetID int) (int64, int64, error) {
+	if targetID == 0 {
+		return 0, 0, &exception.BadRequestError{Message: "target is required for the reassign strategy"}
+	}
+	if isExist := s.Repository.IsExistById(ctx, tx, userID, targetID); !isExist {
+		return 0, 0, &exception.BadRequestError{Message: "target category does not exist"}
+	}
+	subtree, errFind := s.Repository.FindSubtreeIds(ctx, tx, userID, id)
+	if errFind != nil {
+		return 0, 0, errFind
+	}
+	if slices.Contains(subtree, targetID) {
+		return 0, 0, &exception.BadRequestError{Message: "target can not be the category or one of its subcategories"}
 	}
 
-	return nil
+	notes, errNotes := s.NoteRepository.MoveToCategory(ctx, tx, userID, id, targetID)
+	if errNotes != nil {
+		return 0, 0, errNotes
+	}
+	subcategories, errMove := s.Repository.MoveChildren(ctx, tx, userID, id, targetID)
+	if errMove != nil {
+		return 0, 0, errMove
+	}
+
+	return notes, subcategories, nil
+}
+
+func (s *categoryServiceImpl) GetTree(ctx context.Context, userID int) ([]web.CategoryTreeResponse, error) {
+	var categoriesDom []domain.Category
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		categoriesDom, err = s.Repository.FindTree(ctx, tx, userID)
+		return err
+	})
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	return newCategoryTree(categoriesDom), nil
+}
+
+func (s *categoryServiceImpl) GetDescendants(ctx context.Context, userID int, id int) ([]web.CategoryJSON, error) {
+	categories := []web.CategoryJSON{}
+
+	var categoriesDom []domain.Category
+	errTx := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		if isExist := s.Repository.IsExistById(ctx, tx, userID, id); !isExist {
+			return &exception.NotFoundError{Entity: "category"}
+		}
+		var errFind error
+		categoriesDom, errFind = s.Repository.FindDescendants(ctx, tx, userID, id)
+		return errFind
+	})
+	if errTx != nil {
+		return categories, errTx
+	}
+
+	for _, cDom := range categoriesDom {
+		categories = append(categories, newCategoryResponse(cDom))
+	}
+
+	return categories, nil
 }
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"gorm.io/gorm"
+)
+
+const NoteBatchLimit = 500
+
+// noteBatch tracks the results of a batch while it runs. In best-effort mode
+// every write runs inside a savepoint, so a failing item only undoes itself.
+type noteBatch struct {
+	txManager TxManager
+	atomic    bool
+	response  web.NoteBatchResponse
+}
+
+func (b *noteBatch) fail(index int, err error) {
+	errRes := exception.NewErrorResponse(err)
+	b.response.Results[index].Status = web.NoteBatchFailed
+	b.response.Results[index].Note = nil
+	b.response.Results[index].Error = &errRes
+}
+
+func (b *noteBatch) succeed(index int, note web.NoteResponse) {
+	b.response.Results[index].Status = web.NoteBatchOK
+	b.response.Results[index].ID = note.ID
+	if b.response.Results[index].Op != web.NoteBatchDelete {
+		b.response.Results[index].Note = &note
+	}
+}
+
+// run calls write inside a savepoint of tx in best-effort mode. The returned
+// error is the one of write.
+func (b *noteBatch) run(tx *gorm.DB, write func(tx *gorm.DB) error) error {
+	if b.atomic {
+		return write(tx)
+	}
+	return b.txManager.Nested(tx, write)
+}
+
+// finish counts the results. When an atomic batch failed, every item that
+// went through is reported as rolled back.
+func (b *noteBatch) finish(committed bool) web.NoteBatchResponse {
+	b.response.Committed = committed
+	for i, result := range b.response.Results {
+		if result.Status == web.NoteBatchFailed {
+			b.response.Failed++
+			continue
+		}
+		if !committed {
+			b.response.Results[i].Status = web.NoteBatchRolledBack
+			b.response.Results[i].Note = nil
+			continue
+		}
+		b.response.Succeeded++
+	}
+	return b.response
+}
+
+type noteBatchCreate struct {
+	index    int
+	note     web.NoteRequest
+	tags     []string
+	category domain.Category
+}
+
+// checkBatchOperation validates an operation before anything is written and
+// returns the normalized tag names of its note.
+func (s *noteServiceImpl) checkBatchOperation(ctx context.Context, op web.NoteBatchOperation) ([]string, error) {
+	switch op.Op {
+	case web.NoteBatchCreate, web.NoteBatchUpdate, web.NoteBatchDelete:
+	default:
+		return nil, &exception.BadRequestError{Message: "op is invalid"}
+	}
+	if op.Op != web.NoteBatchCreate {
+		if op.ID < 1 {
+			return nil, &exception.NotFoundError{Entity: "note"}
+		}
+		if op.Version < 1 {
+			return nil, &exception.PreconditionRequiredError{Message: "version is required"}
+		}
+	}
+	if op.Op == web.NoteBatchDelete {
+		return nil, nil
+	}
+
+	if op.Note == nil {
+		return nil, &exception.BadRequestError{Message: "note is required"}
+	}
+	if errValidate := validateNote(ctx, s.Validate, *op.Note, s.BodyMaxSize); errValidate != nil {
+		return nil, errValidate
+	}
+	return NormalizeTagNames(op.Note.Tags)
+}
+
+func (s *noteServiceImpl) Batch(ctx context.Context, userID int, request web.NoteBatchRequest) (web.NoteBatchResponse, error) {
+	if request.Mode == "" {
+		request.Mode = web.NoteBatchAtomic
+	}
+	if request.Mode != web.NoteBatchAtomic && request.Mode != web.NoteBatchBestEffort {
+		return web.NoteBatchResponse{}, &exception.BadRequestError{Message: "mode is invalid"}
+	}
+	if len(request.Operations) == 0 {
+		return web.NoteBatchResponse{}, &exception.BadRequestError{Message: "operations is required"}
+	}
+	if len(request.Operations) > NoteBatchLimit {
+		return web.NoteBatchResponse{}, &exception.BadRequestError{
+			Message: fmt.Sprintf("a batch can hold at most %d operations", NoteBatchLimit),
+		}
+	}
+
+	batch := &noteBatch{
+		txManager: s.TxManager,
+		atomic:    request.Mode == web.NoteBatchAtomic,
+		response: web.NoteBatchResponse{
+			Mode:    request.Mode,
+			Results: make([]web.NoteBatchResult, len(request.Operations)),
+		},
+	}
+
+	tagNames := make([][]string, len(request.Operations))
+	var creates []noteBatchCreate
+	var categoryIDs []int
+	for i, op := range request.Operations {
+		batch.response.Results[i] = web.NoteBatchResult{Index: i, Op: op.Op, ID: op.ID}
+		names, errCheck := s.checkBatchOperation(ctx, op)
+		if errCheck != nil {
+			batch.fail(i, errCheck)
+			continue
+		}
+		tagNames[i] = names
+		if op.Op == web.NoteBatchCreate {
+			creates = append(creates, noteBatchCreate{index: i, note: *op.Note, tags: names})
+			categoryIDs = append(categoryIDs, op.Note.CategoryId)
+		}
+	}
+
+	// A failed atomic batch returns ErrRollback, so the unit of work rolls
+	// back while the response still lists what failed.
+	committed := true
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		errWrite := s.writeBatch(ctx, tx, userID, batch, request.Operations, tagNames, creates, categoryIDs)
+		if errors.Is(errWrite, ErrRollback) {
+			committed = false
+		}
+		return errWrite
+	})
+	if errTx != nil {
+		return web.NoteBatchResponse{}, errTx
+	}
+
+	return batch.finish(committed), nil
+}
+
+// writeBatch runs the operations that passed the checks inside tx, in the
+// order they were sent. Consecutive creates are inserted together.
+func (s *noteServiceImpl) writeBatch(ctx context.Context, tx *gorm.DB, userID int, batch *noteBatch, operations []web.NoteBatchOperation,
+	tagNames [][]string, creates []noteBatchCreate, categoryIDs []int) error {
+	// The categories of all new notes are looked up with a single query.
+	valid := make(map[int]noteBatchCreate, len(creates))
+	if len(creates) > 0 {
+		categoriesDom, errFind := s.CategoryRepository.FindByIds(ctx, tx, userID, categoryIDs)
+		if errFind != nil {
+			return errFind
+		}
+		categories := make(map[int]domain.Category, len(categoriesDom))
+		for _, cDom := range categoriesDom {
+			categories[cDom.ID] = cDom
+		}
+
+		for _, create := range creates {
+			category, ok := categories[create.note.CategoryId]
+			if !ok {
+				batch.fail(create.index, &exception.BadRequestError{Message: "category did not exists"})
+				continue
+			}
+			create.category = category
+			valid[create.index] = create
+		}
+	}
+
+	if batch.atomic && countFailed(batch.response.Results) > 0 {
+		return ErrRollback
+	}
+
+	for i := 0; i < len(operations); {
+		if operations[i].Op == web.NoteBatchCreate {
+			var run []noteBatchCreate
+			for ; i < len(operations) && operations[i].Op == web.NoteBatchCreate; i++ {
+				if create, ok := valid[i]; ok {
+					run = append(run, create)
+				}
+			}
+			if errCreate := s.writeCreates(ctx, tx, userID, batch, run); errCreate != nil {
+				return errCreate
+			}
+			continue
+		}
+
+		op := operations[i]
+		index := i
+		i++
+		if batch.response.Results[index].Status == web.NoteBatchFailed {
+			continue
+		}
+
+		var note web.NoteResponse
+		errWrite := batch.run(tx, func(tx *gorm.DB) error {
+			if op.Op == web.NoteBatchDelete {
+				note.ID = op.ID
+				return s.delete(ctx, tx, userID, op.ID, []int{op.Version})
+			}
+
+			noteReq := *op.Note
+			noteReq.ID = op.ID
+			noteReq.Versions = []int{op.Version}
+			var errUpdate error
+			note, errUpdate = s.update(ctx, tx, userID, noteReq, tagNames[index])
+			return errUpdate
+		})
+		if errWrite != nil {
+			batch.fail(index, errWrite)
+			if batch.atomic {
+				return ErrRollback
+			}
+			continue
+		}
+		batch.succeed(index, note)
+	}
+
+	return nil
+}
+
+// writeCreates inserts a run of creates with one batched statement each. When
+// that fails, every create is tried again in a savepoint of its own, so only
+// the ones that can't be written are reported as failed. An atomic batch is
+// rolled back afterwards and reports the others as rolled back.
+func (s *noteServiceImpl) writeCreates(ctx context.Context, tx *gorm.DB, userID int, batch *noteBatch,
+	creates []noteBatchCreate) error {
+	if len(creates) == 0 {
+		return nil
+	}
+
+	// The run gets a savepoint in atomic mode as well, otherwise a failed
+	// insert aborts the transaction and the creates can't be tried again.
+	var notes []web.NoteResponse
+	errCreate := batch.txManager.Nested(tx, func(tx *gorm.DB) error {
+		var errCreate error
+		notes, errCreate = s.createAll(ctx, tx, userID, creates)
+		return errCreate
+	})
+	if errCreate == nil {
+		for i, create := range creates {
+			batch.succeed(create.index, notes[i])
+		}
+		return nil
+	}
+
+	failed := 0
+	if len(creates) > 1 {
+		for _, create := range creates {
+			errCreate := batch.txManager.Nested(tx, func(tx *gorm.DB) error {
+				var errCreate error
+				notes, errCreate = s.createAll(ctx, tx, userID, []noteBatchCreate{create})
+				return errCreate
+			})
+			if errCreate != nil {
+				batch.fail(create.index, errCreate)
+				failed++
+				continue
+			}
+			batch.succeed(create.index, notes[0])
+		}
+	}
+	// The creates can also only fail together, then none of them is to blame
+	// more than the others.
+	if failed == 0 && (batch.atomic || len(creates) == 1) {
+		for _, create := range creates {
+			batch.fail(create.index, errCreate)
+		}
+	}
+
+	if batch.atomic {
+		return ErrRollback
+	}
+	return nil
+}
+
+func countFailed(results []web.NoteBatchResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Status == web.NoteBatchFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// createAll inserts the notes, their first revisions and their tags with one
+// batched statement each.
+func (s *noteServiceImpl) createAll(ctx context.Context, tx *gorm.DB, userID int, creates []noteBatchCreate) ([]web.NoteResponse, error) {
+	notesDom := make([]domain.Note, 0, len(creates))
+	tags := make([][]string, 0, len(creates))
+	for _, create := range creates {
+		notesDom = append(notesDom, domain.Note{
+			Title:      create.note.Title,
+			Body:       create.note.Body,
+			Format:     noteFormat(create.note.Format, web.NoteFormatPlain),
+			CategoryID: create.note.CategoryId,
+			UserID:     userID,
+		})
+		tags = append(tags, create.tags)
+	}
+
+	notesDom, errSave := insertNotes(ctx, tx, s.NoteRepository, s.RevisionRepository, s.TagRepository, userID, notesDom, tags)
+	if errSave != nil {
+		return nil, errSave
+	}
+
+	notes := make([]web.NoteResponse, 0, len(notesDom))
+	for i, nDom := range notesDom {
+		noteTags := append([]string{}, creates[i].tags...)
+		slices.Sort(noteTags)
+		notes = append(notes, web.NoteResponse{
+			ID:        nDom.ID,
+			Title:     nDom.Title,
+			Body:      nDom.Body,
+			Format:    nDom.Format,
+			Category:  creates[i].category.Name,
+			Tags:      noteTags,
+			Version:   nDom.Version,
+			CreatedAt: nDom.CreatedAt,
+			UpdatedAt: nDom.UpdatedAt,
+		})
+	}
+
+	return notes, nil
+}
+
+// insertNotes creates many notes with their first revision and their tags,
+// tags[i] being the normalized tag names of notes[i].
+func insertNotes(ctx context.Context, tx *gorm.DB, noteRepository repository.NoteRepository,
+	revisionRepository repository.NoteRevisionRepository, tagRepository repository.TagRepository, userID int,
+	notes []domain.Note, tags [][]string) ([]domain.Note, error) {
+	notes, errSave := noteRepository.CreateAll(ctx, tx, notes)
+	if errSave != nil {
+		return notes, errSave
+	}
+
+	revisions := make([]domain.NoteRevision, 0, len(notes))
+	for _, nDom := range notes {
+		revisions = append(revisions, newNoteRevision(nDom, userID))
+	}
+	if errSave := revisionRepository.CreateFirst(ctx, tx, revisions); errSave != nil {
+		return notes, errSave
+	}
+
+	var names []string
+	for _, noteTags := range tags {
+		for _, name := range noteTags {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	tagsDom, errFind := tagRepository.FindOrCreate(ctx, tx, userID, names)
+	if errFind != nil {
+		return notes, errFind
+	}
+	tagIDs := make(map[string]int, len(tagsDom))
+	for _, tDom := range tagsDom {
+		tagIDs[tDom.Name] = tDom.ID
+	}
+
+	var noteTags []domain.NoteTag
+	for i, nDom := range notes {
+		for _, name := range tags[i] {
+			noteTags = append(noteTags, domain.NoteTag{NoteID: nDom.ID, TagID: tagIDs[name]})
+		}
+	}
+	if errSave := noteRepository.AddTags(ctx, tx, noteTags); errSave != nil {
+		return notes, errSave
+	}
+
+	return notes, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"gorm.io/gorm"
+)
+
+type NoteRevisionService interface {
+	GetAll(ctx context.Context, userID int, noteID int) ([]web.NoteRevisionResponse, error)
+	GetByRevision(ctx context.Context, userID int, noteID int, revision int) (web.NoteRevisionResponse, error)
+	Diff(ctx context.Context, userID int, noteID int, from int, to int) (web.NoteRevisionDiffResponse, error)
+	Restore(ctx context.Context, userID int, noteID int, revision int) (web.NoteResponse, error)
+}
+
+type noteRevisionServiceImpl struct {
+	TxManager          TxManager
+	NoteRepository     repository.NoteRepository
+	CategoryRepository repository.CategoryRepository
+	RevisionRepository repository.NoteRevisionRepository
+}
+
+func NewNoteRevisionService(txManager TxManager, noteRepository repository.NoteRepository,
+	categoryRepository repository.CategoryRepository, revisionRepository repository.NoteRevisionRepository) *noteRevisionServiceImpl {
+	return &noteRevisionServiceImpl{
+		TxManager:          txManager,
+		NoteRepository:     noteRepository,
+		CategoryRepository: categoryRepository,
+		RevisionRepository: revisionRepository,
+	}
+}
+
+func newNoteRevisionResponse(revision domain.NoteRevision) web.NoteRevisionResponse {
+	return web.NoteRevisionResponse{
+		Revision:   revision.Revision,
+		Title:      revision.Title,
+		Body:       revision.Body,
+		Format:     revision.Format,
+		CategoryID: revision.CategoryID,
+		AuthorID:   revision.AuthorID,
+		CreatedAt:  revision.CreatedAt,
+	}
+}
+
+// revisionDocument is the text the diff is computed on: the title, an empty
+// line and then the body.
+func revisionDocument(revision domain.NoteRevision) string {
+	return revision.Title + "\n\n" + revision.Body
+}
+
+func (s *noteRevisionServiceImpl) GetAll(ctx context.Context, userID int, noteID int) ([]web.NoteRevisionResponse, error) {
+	var revisions []web.NoteRevisionResponse
+
+	var revisionsDom []domain.NoteRevision
+	errTx := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		if isNoteExist := s.NoteRepository.IsExistById(ctx, tx, userID, noteID); !isNoteExist {
+			return &exception.NotFoundError{Entity: "note"}
+		}
+
+		var errFind error
+		revisionsDom, errFind = s.RevisionRepository.FindAll(ctx, tx, noteID)
+		return errFind
+	})
+	if errTx != nil {
+		return revisions, errTx
+	}
+
+	for _, rDom := range revisionsDom {
+		revisions = append(revisions, newNoteRevisionResponse(rDom))
+	}
+
+	return revisions, nil
+}
+
+func (s *noteRevisionServiceImpl) GetByRevision(ctx context.Context, userID int, noteID int, revision int) (web.NoteRevisionResponse, error) {
+	var revisionResponse web.NoteRevisionResponse
+
+	var revisionDom domain.NoteRevision
+	errTx := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		if isNoteExist := s.NoteRepository.IsExistById(ctx, tx, userID, noteID); !isNoteExist {
+			return &exception.NotFoundError{Entity: "note"}
+		}
+
+		var errFind error
+		if revisionDom, errFind = s.RevisionRepository.FindByRevision(ctx, tx, noteID, revision); errFind != nil {
+			return &exception.NotFoundError{Entity: "revision"}
+		}
+		return nil
+	})
+	if errTx != nil {
+		return revisionResponse, errTx
+	}
+
+	revisionResponse = newNoteRevisionResponse(revisionDom)
+	return revisionResponse, nil
+}
+
+func (s *noteRevisionServiceImpl) Diff(ctx context.Context, userID int, noteID int, from int, to int) (web.NoteRevisionDiffResponse, error) {
+	var diff web.NoteRevisionDiffResponse
+
+	var fromDom, toDom domain.NoteRevision
+	errTx := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		if isNoteExist := s.NoteRepository.IsExistById(ctx, tx, userID, noteID); !isNoteExist {
+			return &exception.NotFoundError{Entity: "note"}
+		}
+
+		var errFind error
+		if fromDom, errFind = s.RevisionRepository.FindByRevision(ctx, tx, noteID, from); errFind != nil {
+			return &exception.NotFoundError{Entity: "revision"}
+		}
+		if toDom, errFind = s.RevisionRepository.FindByRevision(ctx, tx, noteID, to); errFind != nil {
+			return &exception.NotFoundError{Entity: "revision"}
+		}
+		return nil
+	})
+	if errTx != nil {
+		return diff, errTx
+	}
+
+	unified, errDiff := helper.UnifiedDiff("revision "+strconv.Itoa(from), "revision "+strconv.Itoa(to),
+		revisionDocument(fromDom), revisionDocument(toDom))
+	if errDiff != nil {
+		return diff, &exception.UnprocessableEntityError{
+			Message: fmt.Sprintf("revisions with more than %d lines can not be compared", helper.MaxDiffLines),
+		}
+	}
+
+	diff = web.NoteRevisionDiffResponse{
+		From: from,
+		To:   to,
+		Diff: unified,
+	}
+	return diff, nil
+}
+
+// Restore writes the content of an older revision back to the note. The
+// restore itself is recorded as a new revision, so it can be undone as well.
+func (s *noteRevisionServiceImpl) Restore(ctx context.Context, userID int, noteID int, revision int) (web.NoteResponse, error) {
+	var noteResponse web.NoteResponse
+
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		noteScan, errFindNote := s.NoteRepository.FindById(ctx, tx, userID, noteID)
+		if errFindNote != nil || noteScan.Title == "" {
+			return &exception.NotFoundError{Entity: "note"}
+		}
+
+		revisionDom, errFind := s.RevisionRepository.FindByRevision(ctx, tx, noteID, revision)
+		if errFind != nil {
+			return &exception.NotFoundError{Entity: "revision"}
+		}
+
+		categoryDom, errFindCategory := s.CategoryRepository.FindById(ctx, tx, userID, revisionDom.CategoryID)
+		if errFindCategory != nil {
+			return &exception.BadRequestError{Message: "category of the revision no longer exists"}
+		}
+
+		noteDom, errUpdate := s.NoteRepository.Save(ctx, tx, domain.Note{
+			ID:         noteID,
+			Title:      revisionDom.Title,
+			Body:       revisionDom.Body,
+			Format:     revisionDom.Format,
+			CategoryID: revisionDom.CategoryID,
+			UserID:     userID,
+			Version:    noteScan.Version,
+			CreatedAt:  noteScan.CreatedAt,
+		})
+		if errUpdate == nil {
+			_, errUpdate = s.RevisionRepository.Save(ctx, tx, newNoteRevision(noteDom, userID))
+		}
+		if errUpdate != nil {
+			return translateVersionError("note", errUpdate)
+		}
+
+		noteResponse = web.NoteResponse{
+			ID:        noteDom.ID,
+			Title:     noteDom.Title,
+			Body:      noteDom.Body,
+			Format:    noteDom.Format,
+			Category:  categoryDom.Name,
+			Tags:      splitTagNames(noteScan.Tags),
+			Version:   noteDom.Version,
+			CreatedAt: noteDom.CreatedAt,
+			UpdatedAt: noteDom.UpdatedAt,
+		}
+		return nil
+	})
+	if errTx != nil {
+		return web.NoteResponse{}, errTx
+	}
+
+	return noteResponse, nil
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/helper"
 	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"github.com/naomigrain/echo-crud-notes/model/web"
 	"github.com/naomigrain/echo-crud-notes/repository"
@@ -10,162 +14,428 @@ import (
 )
 
 type NoteService interface {
-	GetAll(page int, pageSize int) ([]web.NoteResponse, error)
-	GetById(id int) (web.NoteResponse, error)
-	Create(note web.NoteRequest) (web.NoteResponse, error)
-	Update(note web.NoteRequest) (web.NoteResponse, error)
-	Delete(id int) error
+	GetAll(ctx context.Context, userID int, spec helper.QuerySpec) ([]web.NoteResponse, helper.PageInfo, error)
+	Search(ctx context.Context, userID int, query string, spec helper.QuerySpec) ([]web.NoteResponse, helper.PageInfo, error)
+	GetById(ctx context.Context, userID int, id int, render string) (web.NoteResponse, error)
+	Create(ctx context.Context, userID int, note web.NoteRequest) (web.NoteResponse, error)
+	Update(ctx context.Context, userID int, note web.NoteRequest) (web.NoteResponse, error)
+	Delete(ctx context.Context, userID int, id int, versions []int) error
+	GetTrash(ctx context.Context, userID int, spec helper.QuerySpec) ([]web.NoteResponse, helper.PageInfo, error)
+	Restore(ctx context.Context, userID int, id int) (web.NoteResponse, error)
+	Purge(ctx context.Context, userID int, id int) error
+	Batch(ctx context.Context, userID int, request web.NoteBatchRequest) (web.NoteBatchResponse, error)
 }
 
 type noteServiceImpl struct {
-	DB                 *gorm.DB
+	TxManager          TxManager
 	Validate           *validator.Validate
+	BodyMaxSize        int
 	NoteRepository     repository.NoteRepository
 	CategoryRepository repository.CategoryRepository
+	RevisionRepository repository.NoteRevisionRepository
+	TagRepository      repository.TagRepository
 }
 
-func NewNoteRepositoryImpl(db *gorm.DB, validate *validator.Validate, noteRepository repository.NoteRepository,
-	categoryRepository repository.CategoryRepository) *noteServiceImpl {
+func NewNoteRepositoryImpl(txManager TxManager, validate *validator.Validate, bodyMaxSize int,
+	noteRepository repository.NoteRepository, categoryRepository repository.CategoryRepository,
+	revisionRepository repository.NoteRevisionRepository, tagRepository repository.TagRepository) *noteServiceImpl {
 	return &noteServiceImpl{
-		DB:                 db,
+		TxManager:          txManager,
 		Validate:           validate,
+		BodyMaxSize:        bodyMaxSize,
 		NoteRepository:     noteRepository,
 		CategoryRepository: categoryRepository,
+		RevisionRepository: revisionRepository,
+		TagRepository:      tagRepository,
+	}
+}
+
+func newNoteRevision(note domain.Note, authorID int) domain.NoteRevision {
+	return domain.NoteRevision{
+		NoteID:     note.ID,
+		Title:      note.Title,
+		Body:       note.Body,
+		Format:     note.Format,
+		CategoryID: note.CategoryID,
+		AuthorID:   authorID,
+	}
+}
+
+func newNoteResponse(nS domain.ScanNote) web.NoteResponse {
+	return web.NoteResponse{
+		ID:        nS.ID,
+		Title:     nS.Title,
+		Body:      nS.Body,
+		Format:    nS.Format,
+		Category:  nS.Category,
+		Tags:      splitTagNames(nS.Tags),
+		Version:   nS.Version,
+		CreatedAt: nS.CreatedAt,
+		UpdatedAt: nS.UpdatedAt,
+		DeletedAt: nS.DeletedAt,
+		Snippet:   nS.Snippet,
+	}
+}
+
+// newNoteListResponse adds the excerpt that lists show instead of rendering
+// every body.
+func newNoteListResponse(nS domain.ScanNote) web.NoteResponse {
+	note := newNoteResponse(nS)
+	note.Excerpt = helper.Excerpt(nS.Format, nS.Body)
+	note.BodyTruncated = nS.BodyTruncated
+	return note
+}
+
+// validateNote checks the request against its validate tags and the body
+// against the configured size, which is counted in bytes.
+func validateNote(ctx context.Context, validate *validator.Validate, note web.NoteRequest, bodyMaxSize int) error {
+	if errValidate := validate.StructCtx(ctx, note); errValidate != nil {
+		// A tag can fail because its lookup was cancelled with ctx.
+		if errCtx := ctx.Err(); errCtx != nil {
+			return errCtx
+		}
+		return errValidate
+	}
+	if len(note.Body) > bodyMaxSize {
+		return &exception.BadRequestError{Message: fmt.Sprintf("Body is should below than %d bytes", bodyMaxSize)}
+	}
+	return nil
+}
+
+// noteFormat falls back to current when the request leaves the format out.
+func noteFormat(format string, current string) string {
+	if format == "" {
+		return current
+	}
+	return format
+}
+
+// replaceTags attaches exactly the named tags to the note, creating the ones
+// the user doesn't have yet, and returns their names in order.
+func (s *noteServiceImpl) replaceTags(ctx context.Context, tx *gorm.DB, userID int, noteID int, names []string) ([]string, error) {
+	tagNames := []string{}
+	tagsDom, errFind := s.TagRepository.FindOrCreate(ctx, tx, userID, names)
+	if errFind != nil {
+		return tagNames, errFind
+	}
+
+	tagIDs := make([]int, 0, len(tagsDom))
+	for _, tDom := range tagsDom {
+		tagIDs = append(tagIDs, tDom.ID)
+		tagNames = append(tagNames, tDom.Name)
+	}
+	if errReplace := s.NoteRepository.ReplaceTags(ctx, tx, noteID, tagIDs); errReplace != nil {
+		return tagNames, errReplace
+	}
+
+	return tagNames, nil
+}
+
+func (s *noteServiceImpl) GetAll(ctx context.Context, userID int, spec helper.QuerySpec) ([]web.NoteResponse, helper.PageInfo, error) {
+	var notes []web.NoteResponse
+	if errSpec := spec.Validate(repository.NoteQueryFields); errSpec != nil {
+		return notes, helper.PageInfo{}, &exception.BadRequestError{Message: errSpec.Error()}
+	}
+
+	var notesScan []domain.ScanNote
+	var info helper.PageInfo
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		notesScan, info, err = s.NoteRepository.FindAll(ctx, tx, userID, spec)
+		return err
+	})
+	if errFind != nil {
+		return notes, info, errFind
 	}
+
+	for _, nS := range notesScan {
+		notes = append(notes, newNoteListResponse(nS))
+	}
+
+	return notes, info, nil
 }
 
-func (s *noteServiceImpl) GetAll(page int, pageSize int) ([]web.NoteResponse, error) {
+func (s *noteServiceImpl) Search(ctx context.Context, userID int,
+	query string, spec helper.QuerySpec) ([]web.NoteResponse, helper.PageInfo, error) {
 	var notes []web.NoteResponse
+	if errSpec := spec.Validate(repository.NoteQueryFields); errSpec != nil {
+		return notes, helper.PageInfo{}, &exception.BadRequestError{Message: errSpec.Error()}
+	}
 
-	tx := s.DB.Begin()
-	notesScan, errFind := s.NoteRepository.FindAll(tx, page, pageSize)
+	var notesScan []domain.ScanNote
+	var info helper.PageInfo
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		notesScan, info, err = s.NoteRepository.Search(ctx, tx, userID, query, spec)
+		return err
+	})
 	if errFind != nil {
-		return notes, errFind
+		return notes, info, errFind
 	}
 
 	for _, nS := range notesScan {
-		notes = append(notes, web.NoteResponse{
-			ID:       nS.ID,
-			Title:    nS.Title,
-			Body:     nS.Body,
-			Category: nS.Category,
-		})
+		notes = append(notes, newNoteListResponse(nS))
 	}
 
-	return notes, nil
+	return notes, info, nil
 }
 
-func (s *noteServiceImpl) GetById(id int) (web.NoteResponse, error) {
+func (s *noteServiceImpl) GetById(ctx context.Context, userID int, id int, render string) (web.NoteResponse, error) {
 	var note web.NoteResponse
 
-	tx := s.DB.Begin()
-	noteScan, errFind := s.NoteRepository.FindById(tx, id)
-	if errFind != nil || noteScan.Title == "" {
-		return note, &exception.NotFoundError{Entity: "note"}
+	var noteScan domain.ScanNote
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		noteScan, err = s.NoteRepository.FindById(ctx, tx, userID, id)
+		if err != nil || noteScan.Title == "" {
+			return &exception.NotFoundError{Entity: "note"}
+		}
+		return nil
+	})
+	if errFind != nil {
+		return note, errFind
 	}
 
-	note = web.NoteResponse{
-		ID:       id,
-		Title:    noteScan.Title,
-		Body:     noteScan.Body,
-		Category: noteScan.Category,
+	note = newNoteResponse(noteScan)
+	if render == web.NoteRenderHTML {
+		note.HTML = helper.RenderHTML(note.Format, note.Body)
 	}
 	return note, nil
 }
 
-func (s *noteServiceImpl) Create(note web.NoteRequest) (web.NoteResponse, error) {
+func (s *noteServiceImpl) Create(ctx context.Context, userID int, note web.NoteRequest) (web.NoteResponse, error) {
 	var noteResponse web.NoteResponse
-	if errValidate := s.Validate.Struct(note); errValidate != nil {
+	if errValidate := validateNote(withValidationUser(ctx, userID), s.Validate, note, s.BodyMaxSize); errValidate != nil {
 		return noteResponse, errValidate
 	}
-
-	tx := s.DB.Begin()
-	categoryDom, errFind := s.CategoryRepository.FindById(tx, note.CategoryId)
-	if errFind != nil {
-		return noteResponse, &exception.BadRequestError{Message: "category did not exists"}
+	tagNames, errTags := NormalizeTagNames(note.Tags)
+	if errTags != nil {
+		return noteResponse, errTags
 	}
 
-	noteDom, errSave := s.NoteRepository.Save(tx, domain.Note{
-		Title:      note.Title,
-		Body:       note.Body,
-		CategoryID: note.CategoryId,
-	})
-	if errSave != nil {
-		if errRollback := tx.Rollback().Error; errRollback != nil {
-			return noteResponse, errRollback
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		categoryDom, errFind := s.CategoryRepository.FindById(ctx, tx, userID, note.CategoryId)
+		if errFind != nil {
+			return &exception.BadRequestError{Message: "category did not exists"}
 		}
-		return noteResponse, errSave
-	}
-	if errCommit := tx.Commit().Error; errCommit != nil {
-		return noteResponse, errCommit
-	}
 
-	noteResponse = web.NoteResponse{
-		ID:       noteDom.ID,
-		Title:    note.Title,
-		Body:     note.Body,
-		Category: categoryDom.Name,
+		noteDom, errSave := s.NoteRepository.Save(ctx, tx, domain.Note{
+			Title:      note.Title,
+			Body:       note.Body,
+			Format:     noteFormat(note.Format, web.NoteFormatPlain),
+			CategoryID: note.CategoryId,
+			UserID:     userID,
+		})
+		if errSave != nil {
+			return errSave
+		}
+		if _, errSave = s.RevisionRepository.Save(ctx, tx, newNoteRevision(noteDom, userID)); errSave != nil {
+			return errSave
+		}
+		if tagNames, errSave = s.replaceTags(ctx, tx, userID, noteDom.ID, tagNames); errSave != nil {
+			return errSave
+		}
+
+		noteResponse = web.NoteResponse{
+			ID:        noteDom.ID,
+			Title:     note.Title,
+			Body:      note.Body,
+			Format:    noteDom.Format,
+			Category:  categoryDom.Name,
+			Tags:      tagNames,
+			Version:   noteDom.Version,
+			CreatedAt: noteDom.CreatedAt,
+			UpdatedAt: noteDom.UpdatedAt,
+		}
+		return nil
+	})
+	if errTx != nil {
+		return web.NoteResponse{}, errTx
 	}
+
 	return noteResponse, nil
 }
 
-func (s *noteServiceImpl) Update(note web.NoteRequest) (web.NoteResponse, error) {
+func (s *noteServiceImpl) Update(ctx context.Context, userID int, note web.NoteRequest) (web.NoteResponse, error) {
 	var noteResponse web.NoteResponse
-	if errVal := s.Validate.Struct(note); errVal != nil {
+	if errVal := validateNote(withValidationUser(ctx, userID), s.Validate, note, s.BodyMaxSize); errVal != nil {
 		return noteResponse, errVal
 	}
+	newTagNames, errTags := NormalizeTagNames(note.Tags)
+	if errTags != nil {
+		return noteResponse, errTags
+	}
+
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		var errUpdate error
+		noteResponse, errUpdate = s.update(ctx, tx, userID, note, newTagNames)
+		return errUpdate
+	})
+	if errTx != nil {
+		return web.NoteResponse{}, errTx
+	}
 
-	tx := s.DB.Begin()
-	if isNoteExist := s.NoteRepository.IsExistById(tx, note.ID); !isNoteExist {
+	return noteResponse, nil
+}
+
+// update writes an already validated note inside the unit of work of the
+// caller.
+func (s *noteServiceImpl) update(ctx context.Context, tx *gorm.DB, userID int,
+	note web.NoteRequest, newTagNames []string) (web.NoteResponse, error) {
+	var noteResponse web.NoteResponse
+	noteScan, errFindNote := s.NoteRepository.FindById(ctx, tx, userID, note.ID)
+	if errFindNote != nil || noteScan.Title == "" {
 		return noteResponse, &exception.NotFoundError{Entity: "note"}
 	}
+	if errVersion := checkVersion("note", note.Versions, noteScan.Version); errVersion != nil {
+		return noteResponse, errVersion
+	}
 
-	categoryDom, errFind := s.CategoryRepository.FindById(tx, note.CategoryId)
+	categoryDom, errFind := s.CategoryRepository.FindById(ctx, tx, userID, note.CategoryId)
 	if errFind != nil {
 		return noteResponse, &exception.BadRequestError{Message: "category does not exists"}
 	}
 
-	noteDom, errUpdate := s.NoteRepository.Save(tx, domain.Note{
+	// Notes written before revisions existed get their current state recorded
+	// first, so the update can still be rolled back.
+	if hasRevisions := s.RevisionRepository.IsExistByNoteId(ctx, tx, note.ID); !hasRevisions {
+		initial := newNoteRevision(domain.Note{
+			ID:         noteScan.ID,
+			Title:      noteScan.Title,
+			Body:       noteScan.Body,
+			Format:     noteScan.Format,
+			CategoryID: noteScan.CategoryID,
+		}, userID)
+		initial.CreatedAt = noteScan.UpdatedAt
+		if _, errSave := s.RevisionRepository.Save(ctx, tx, initial); errSave != nil {
+			return noteResponse, errSave
+		}
+	}
+
+	noteDom, errUpdate := s.NoteRepository.Save(ctx, tx, domain.Note{
 		ID:         note.ID,
 		Title:      note.Title,
 		Body:       note.Body,
+		Format:     noteFormat(note.Format, noteScan.Format),
 		CategoryID: note.CategoryId,
+		UserID:     userID,
+		Version:    noteScan.Version,
+		CreatedAt:  noteScan.CreatedAt,
 	})
-	if errUpdate != nil {
-		if errRollback := tx.Rollback().Error; errRollback != nil {
-			return noteResponse, errRollback
-		}
-		return noteResponse, errUpdate
+	if errUpdate == nil {
+		_, errUpdate = s.RevisionRepository.Save(ctx, tx, newNoteRevision(noteDom, userID))
+	}
+	// Tags are only replaced when the request sends them, an empty list
+	// removes all of them.
+	tagNames := splitTagNames(noteScan.Tags)
+	if errUpdate == nil && note.Tags != nil {
+		tagNames, errUpdate = s.replaceTags(ctx, tx, userID, noteDom.ID, newTagNames)
 	}
-	if errCommit := tx.Commit().Error; errCommit != nil {
-		return noteResponse, errCommit
+	if errUpdate != nil {
+		return noteResponse, translateVersionError("note", errUpdate)
 	}
 
 	noteResponse = web.NoteResponse{
-		ID:       noteDom.ID,
-		Title:    note.Title,
-		Body:     note.Body,
-		Category: categoryDom.Name,
+		ID:        noteDom.ID,
+		Title:     note.Title,
+		Body:      note.Body,
+		Format:    noteDom.Format,
+		Category:  categoryDom.Name,
+		Tags:      tagNames,
+		Version:   noteDom.Version,
+		CreatedAt: noteDom.CreatedAt,
+		UpdatedAt: noteDom.UpdatedAt,
 	}
 	return noteResponse, nil
 }
 
-func (s *noteServiceImpl) Delete(id int) error {
-	tx := s.DB.Begin()
-	if isNoteExist := s.NoteRepository.IsExistById(tx, id); !isNoteExist {
+func (s *noteServiceImpl) Delete(ctx context.Context, userID int, id int, versions []int) error {
+	return s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		return s.delete(ctx, tx, userID, id, versions)
+	})
+}
+
+func (s *noteServiceImpl) delete(ctx context.Context, tx *gorm.DB, userID int, id int, versions []int) error {
+	noteScan, errFind := s.NoteRepository.FindById(ctx, tx, userID, id)
+	if errFind != nil || noteScan.Title == "" {
 		return &exception.NotFoundError{Entity: "note"}
 	}
-
-	errDel := s.NoteRepository.Delete(tx, id)
-	if errDel != nil {
-		if errRollback := tx.Rollback().Error; errRollback != nil {
-			return errRollback
-		}
-		return errDel
+	if errVersion := checkVersion("note", versions, noteScan.Version); errVersion != nil {
+		return errVersion
 	}
-	if errCommit := tx.Commit().Error; errCommit != nil {
-		return errCommit
+
+	if errDel := s.NoteRepository.Delete(ctx, tx, userID, id, noteScan.Version); errDel != nil {
+		return translateVersionError("note", errDel)
 	}
 
 	return nil
 }
+
+func (s *noteServiceImpl) GetTrash(ctx context.Context, userID int, spec helper.QuerySpec) ([]web.NoteResponse, helper.PageInfo, error) {
+	var notes []web.NoteResponse
+	if len(spec.Sorts) == 0 {
+		spec.Sorts = []helper.SortSpec{{Field: "deleted_at", Desc: true}}
+	}
+	if errSpec := spec.Validate(repository.NoteTrashQueryFields); errSpec != nil {
+		return notes, helper.PageInfo{}, &exception.BadRequestError{Message: errSpec.Error()}
+	}
+
+	var notesScan []domain.ScanNote
+	var info helper.PageInfo
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		notesScan, info, err = s.NoteRepository.FindTrash(ctx, tx, userID, spec)
+		return err
+	})
+	if errFind != nil {
+		return notes, info, errFind
+	}
+
+	for _, nS := range notesScan {
+		notes = append(notes, newNoteListResponse(nS))
+	}
+
+	return notes, info, nil
+}
+
+// Restore takes the note out of the trash. If its category was trashed in the
+// meantime, the category is restored with it.
+func (s *noteServiceImpl) Restore(ctx context.Context, userID int, id int) (web.NoteResponse, error) {
+	var note web.NoteResponse
+
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		noteDom, errFind := s.NoteRepository.FindTrashedById(ctx, tx, userID, id)
+		if errFind != nil {
+			return &exception.NotFoundError{Entity: "note"}
+		}
+
+		if errRestore := s.CategoryRepository.Restore(ctx, tx, userID, noteDom.CategoryID); errRestore != nil {
+			return errRestore
+		}
+		if errRestore := s.NoteRepository.Restore(ctx, tx, userID, id); errRestore != nil {
+			return errRestore
+		}
+
+		noteScan, errFind := s.NoteRepository.FindById(ctx, tx, userID, id)
+		if errFind != nil {
+			return errFind
+		}
+		note = newNoteResponse(noteScan)
+		return nil
+	})
+	if errTx != nil {
+		return web.NoteResponse{}, errTx
+	}
+
+	return note, nil
+}
+
+func (s *noteServiceImpl) Purge(ctx context.Context, userID int, id int) error {
+	return s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		if _, errFind := s.NoteRepository.FindTrashedById(ctx, tx, userID, id); errFind != nil {
+			return &exception.NotFoundError{Entity: "note"}
+		}
+
+		return s.NoteRepository.Purge(ctx, tx, userID, id)
+	})
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"gorm.io/gorm"
+)
+
+type TagService interface {
+	GetAll(ctx context.Context, userID int) ([]web.TagResponse, error)
+	GetById(ctx context.Context, userID int, id int) (web.TagResponse, error)
+	Create(ctx context.Context, userID int, tag web.TagRequest) (web.TagResponse, error)
+	Update(ctx context.Context, userID int, tag web.TagRequest) (web.TagResponse, error)
+	Delete(ctx context.Context, userID int, id int) error
+}
+
+type tagServiceImpl struct {
+	TxManager  TxManager
+	Validate   *validator.Validate
+	Repository repository.TagRepository
+}
+
+func NewTagService(txManager TxManager, validate *validator.Validate, repository repository.TagRepository) *tagServiceImpl {
+	return &tagServiceImpl{
+		TxManager:  txManager,
+		Validate:   validate,
+		Repository: repository,
+	}
+}
+
+func newTagResponse(tag domain.Tag) web.TagResponse {
+	return web.TagResponse{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		CreatedAt: tag.CreatedAt,
+		UpdatedAt: tag.UpdatedAt,
+	}
+}
+
+// NormalizeTagNames lowercases and trims tag names and drops duplicates. Tags
+// are aggregated as a comma separated list, so a name can't contain a comma.
+func NormalizeTagNames(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return nil, &exception.BadRequestError{Message: "tag name can not be empty"}
+		}
+		if strings.Contains(name, ",") {
+			return nil, &exception.BadRequestError{Message: "tag name can not contain a comma"}
+		}
+		if !slices.Contains(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+
+	return normalized, nil
+}
+
+func splitTagNames(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+	return strings.Split(tags, ",")
+}
+
+func (s *tagServiceImpl) GetAll(ctx context.Context, userID int) ([]web.TagResponse, error) {
+	tags := []web.TagResponse{}
+
+	var tagsDom []domain.Tag
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		tagsDom, err = s.Repository.FindAll(ctx, tx, userID)
+		return err
+	})
+	if errFind != nil {
+		return tags, errFind
+	}
+
+	for _, tDom := range tagsDom {
+		tags = append(tags, newTagResponse(tDom))
+	}
+
+	return tags, nil
+}
+
+func (s *tagServiceImpl) GetById(ctx context.Context, userID int, id int) (web.TagResponse, error) {
+	var tag web.TagResponse
+
+	var tagDom domain.Tag
+	errFind := s.TxManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+		var err error
+		tagDom, err = s.Repository.FindById(ctx, tx, userID, id)
+		if err != nil {
+			return &exception.NotFoundError{Entity: "tag"}
+		}
+		return nil
+	})
+	if errFind != nil {
+		return tag, errFind
+	}
+
+	tag = newTagResponse(tagDom)
+	return tag, nil
+}
+
+func (s *tagServiceImpl) Create(ctx context.Context, userID int, tag web.TagRequest) (web.TagResponse, error) {
+	var tagResponse web.TagResponse
+	if errValidate := s.Validate.Struct(tag); errValidate != nil {
+		return tagResponse, errValidate
+	}
+	names, errNormalize := NormalizeTagNames([]string{tag.Name})
+	if errNormalize != nil {
+		return tagResponse, errNormalize
+	}
+
+	var tagDom domain.Tag
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		if isExist := s.Repository.IsExistByName(ctx, tx, userID, names[0]); isExist {
+			return &exception.BadRequestError{Message: "tag already exists"}
+		}
+
+		var errSave error
+		tagDom, errSave = s.Repository.Save(ctx, tx, domain.Tag{
+			Name:   names[0],
+			UserID: userID,
+		})
+		return errSave
+	})
+	if errTx != nil {
+		return tagResponse, errTx
+	}
+
+	tagResponse = newTagResponse(tagDom)
+	return tagResponse, nil
+}
+
+func (s *tagServiceImpl) Update(ctx context.Context, userID int, tag web.TagRequest) (web.TagResponse, error) {
+	var tagResponse web.TagResponse
+	if errValidate := s.Validate.Struct(tag); errValidate != nil {
+		return tagResponse, errValidate
+	}
+	names, errNormalize := NormalizeTagNames([]string{tag.Name})
+	if errNormalize != nil {
+		return tagResponse, errNormalize
+	}
+
+	var tagDom domain.Tag
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		var errFind error
+		tagDom, errFind = s.Repository.FindById(ctx, tx, userID, tag.ID)
+		if errFind != nil {
+			return &exception.NotFoundError{Entity: "tag"}
+		}
+		if tagDom.Name != names[0] && s.Repository.IsExistByName(ctx, tx, userID, names[0]) {
+			return &exception.BadRequestError{Message: "tag already exists"}
+		}
+
+		tagDom.Name = names[0]
+		var errSave error
+		tagDom, errSave = s.Repository.Save(ctx, tx, tagDom)
+		return errSave
+	})
+	if errTx != nil {
+		return tagResponse, errTx
+	}
+
+	tagResponse = newTagResponse(tagDom)
+	return tagResponse, nil
+}
+
+// Delete removes the tag from every note it was attached to as well.
+func (s *tagServiceImpl) Delete(ctx context.Context, userID int, id int) error {
+	return s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		if _, errFind := s.Repository.FindById(ctx, tx, userID, id); errFind != nil {
+			return &exception.NotFoundError{Entity: "tag"}
+		}
+
+		return s.Repository.Delete(ctx, tx, userID, id)
+	})
+}
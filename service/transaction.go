@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// ErrRollback can be returned by a unit of work to roll it back without
+// failing, for example for a dry run. Do, ReadOnly and Nested return nil then.
+var ErrRollback = errors.New("rollback requested")
+
+// PanicError is returned when a unit of work panicked. The transaction is
+// rolled back and the stack of the panic is kept for logging.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("transaction panicked: %v", e.Value)
+}
+
+// StackTrace lets the error handler log where the panic happened.
+func (e *PanicError) StackTrace() []byte {
+	return e.Stack
+}
+
+// TxManager runs the work of a service method as a unit: everything fn writes
+// is committed when it returns nil and rolled back when it returns an error or
+// panics, so no path can leave a transaction open.
+type TxManager interface {
+	// Do runs fn in a read-write transaction.
+	Do(ctx context.Context, fn func(tx *gorm.DB) error) error
+	// ReadOnly runs fn in a transaction that refuses writes.
+	ReadOnly(ctx context.Context, fn func(tx *gorm.DB) error) error
+	// Run runs fn in a transaction started with opts, which may be nil. The
+	// transaction is bound to ctx, so cancelling ctx cancels its queries.
+	Run(ctx context.Context, opts *sql.TxOptions, fn func(tx *gorm.DB) error) error
+	// Nested runs fn in a savepoint of tx, which has to be a transaction. An
+	// error or panic in fn only undoes what fn wrote, tx stays usable.
+	Nested(tx *gorm.DB, fn func(tx *gorm.DB) error) error
+}
+
+type txManagerImpl struct {
+	DB         *gorm.DB
+	savepoints atomic.Uint64
+}
+
+func NewTxManager(db *gorm.DB) *txManagerImpl {
+	return &txManagerImpl{
+		DB: db,
+	}
+}
+
+func (m *txManagerImpl) Do(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.Run(ctx, nil, fn)
+}
+
+func (m *txManagerImpl) ReadOnly(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.Run(ctx, &sql.TxOptions{ReadOnly: true}, fn)
+}
+
+func (m *txManagerImpl) Run(ctx context.Context, opts *sql.TxOptions, fn func(tx *gorm.DB) error) error {
+	db := m.DB.WithContext(ctx)
+	var tx *gorm.DB
+	if opts != nil {
+		tx = db.Begin(opts)
+	} else {
+		tx = db.Begin()
+	}
+	if tx.Error != nil {
+		return contextError(ctx, tx.Error)
+	}
+
+	if errWork := call(tx, fn); errWork != nil {
+		// The error of the work says more than a failed rollback, which
+		// postgres does on its own when the connection goes away.
+		tx.Rollback()
+		if errors.Is(errWork, ErrRollback) {
+			return nil
+		}
+		return contextError(ctx, errWork)
+	}
+	return contextError(ctx, tx.Commit().Error)
+}
+
+func (m *txManagerImpl) Nested(tx *gorm.DB, fn func(tx *gorm.DB) error) error {
+	name := fmt.Sprintf("sp_%d", m.savepoints.Add(1))
+	if errSavePoint := tx.SavePoint(name).Error; errSavePoint != nil {
+		return errSavePoint
+	}
+
+	if errWork := call(tx, fn); errWork != nil {
+		if errRollback := tx.RollbackTo(name).Error; errRollback != nil {
+			return errRollback
+		}
+		if errors.Is(errWork, ErrRollback) {
+			return nil
+		}
+		return errWork
+	}
+	return tx.Exec("RELEASE SAVEPOINT " + name).Error
+}
+
+// contextError makes sure an error caused by ctx being cancelled or timing out
+// wraps the error of ctx, as the driver doesn't always report it.
+func contextError(ctx context.Context, err error) error {
+	errCtx := ctx.Err()
+	if err == nil || errCtx == nil || errors.Is(err, errCtx) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", errCtx, err)
+}
+
+// call runs fn and turns a panic into a PanicError.
+func call(tx *gorm.DB, fn func(tx *gorm.DB) error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &PanicError{Value: value, Stack: debug.Stack()}
+		}
+	}()
+	return fn(tx)
+}
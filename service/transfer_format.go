@@ -0,0 +1,428 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+)
+
+// ImportMaxSize caps the size of an import, and of all the files of a
+// Markdown archive together once they are unpacked.
+const ImportMaxSize = 32 << 20
+
+// ImportMaxFiles caps the number of files in a Markdown archive.
+const ImportMaxFiles = 10000
+
+// markdownCategories is the file of a Markdown archive that keeps the
+// categories, so empty categories and the nesting survive a round trip.
+const markdownCategories = "categories.jsonl"
+
+var transferColumns = []string{
+	"type", "id", "name", "parent_id", "title", "body", "format", "category_id", "category", "tags", "created_at", "updated_at",
+}
+
+// transferWriter writes records in one of the export formats. Categories have
+// to be written before the notes, parents before their children.
+type transferWriter interface {
+	Write(record web.TransferRecord) error
+	Close() error
+}
+
+func IsTransferFormat(format string) bool {
+	return format == web.FormatJSONL || format == web.FormatCSV || format == web.FormatMarkdown
+}
+
+func newTransferWriter(format string, w io.Writer) (transferWriter, error) {
+	switch format {
+	case web.FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	case web.FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(transferColumns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{writer: writer}, nil
+	case web.FormatMarkdown:
+		return &markdownWriter{zip: zip.NewWriter(w), dirs: map[int]string{}, used: map[string]bool{}}, nil
+	default:
+		return nil, &exception.BadRequestError{Message: "format is invalid"}
+	}
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(record web.TransferRecord) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(record web.TransferRecord) error {
+	parentID := ""
+	if record.ParentID != nil {
+		parentID = strconv.Itoa(*record.ParentID)
+	}
+	categoryID := ""
+	if record.CategoryID != 0 {
+		categoryID = strconv.Itoa(record.CategoryID)
+	}
+
+	return w.writer.Write([]string{
+		record.Type,
+		strconv.Itoa(record.ID),
+		record.Name,
+		parentID,
+		record.Title,
+		record.Body,
+		record.Format,
+		categoryID,
+		record.Category,
+		strings.Join(record.Tags, ","),
+		record.CreatedAt.Format(time.RFC3339Nano),
+		record.UpdatedAt.Format(time.RFC3339Nano),
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// markdownWriter writes a zip with a directory per category and a Markdown
+// file with front matter per note.
+type markdownWriter struct {
+	zip        *zip.Writer
+	categories io.Writer
+	dirs       map[int]string
+	used       map[string]bool
+}
+
+func (w *markdownWriter) Write(record web.TransferRecord) error {
+	if record.Type == web.RecordCategory {
+		return w.writeCategory(record)
+	}
+
+	dir, ok := w.dirs[record.CategoryID]
+	if !ok {
+		dir = w.uniquePath(".", record.Category, record.CategoryID)
+		w.dirs[record.CategoryID] = dir
+	}
+	file, errCreate := w.zip.Create(path.Join(dir, fmt.Sprintf("%d-%s.md", record.ID, pathName(record.Title))))
+	if errCreate != nil {
+		return errCreate
+	}
+	_, errWrite := file.Write(marshalMarkdownNote(record))
+	return errWrite
+}
+
+func (w *markdownWriter) writeCategory(record web.TransferRecord) error {
+	if w.categories == nil {
+		file, errCreate := w.zip.Create(markdownCategories)
+		if errCreate != nil {
+			return errCreate
+		}
+		w.categories = file
+	}
+
+	parent := "."
+	if record.ParentID != nil {
+		if dir, ok := w.dirs[*record.ParentID]; ok {
+			parent = dir
+		}
+	}
+	w.dirs[record.ID] = w.uniquePath(parent, record.Name, record.ID)
+
+	return json.NewEncoder(w.categories).Encode(record)
+}
+
+// uniquePath adds the id to a directory name that is already taken by a
+// category with the same name.
+func (w *markdownWriter) uniquePath(parent string, name string, id int) string {
+	dir := path.Join(parent, pathName(name))
+	if w.used[dir] {
+		dir = fmt.Sprintf("%s-%d", dir, id)
+	}
+	w.used[dir] = true
+	return dir
+}
+
+func (w *markdownWriter) Close() error {
+	return w.zip.Close()
+}
+
+// pathName turns a name into something every file system accepts.
+func pathName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		return "untitled"
+	}
+	return name
+}
+
+// marshalMarkdownNote writes the note as front matter followed by the body.
+// Strings are quoted as JSON, which YAML readers understand as well.
+func marshalMarkdownNote(record web.TransferRecord) []byte {
+	quote := func(value interface{}) string {
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+	tags := record.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	fmt.Fprintf(&buf, "id: %d\n", record.ID)
+	fmt.Fprintf(&buf, "title: %s\n", quote(record.Title))
+	fmt.Fprintf(&buf, "format: %s\n", quote(record.Format))
+	fmt.Fprintf(&buf, "category_id: %d\n", record.CategoryID)
+	fmt.Fprintf(&buf, "category: %s\n", quote(record.Category))
+	fmt.Fprintf(&buf, "tags: %s\n", quote(tags))
+	fmt.Fprintf(&buf, "created_at: %s\n", record.CreatedAt.Format(time.RFC3339Nano))
+	fmt.Fprintf(&buf, "updated_at: %s\n", record.UpdatedAt.Format(time.RFC3339Nano))
+	buf.WriteString("---\n")
+	buf.WriteString(record.Body)
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+func unmarshalMarkdownNote(data []byte) (web.TransferRecord, error) {
+	record := web.TransferRecord{Type: web.RecordNote}
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return record, fmt.Errorf("front matter is missing")
+	}
+	frontMatter, body, found := strings.Cut(content[len("---\n"):], "\n---\n")
+	if !found {
+		return record, fmt.Errorf("front matter is not closed")
+	}
+	record.Body = strings.TrimSuffix(body, "\n")
+
+	for _, line := range strings.Split(frontMatter, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		var errValue error
+		switch strings.TrimSpace(key) {
+		case "id":
+			record.ID, errValue = strconv.Atoi(value)
+		case "title":
+			errValue = json.Unmarshal([]byte(value), &record.Title)
+		case "format":
+			errValue = json.Unmarshal([]byte(value), &record.Format)
+		case "category_id":
+			record.CategoryID, errValue = strconv.Atoi(value)
+		case "category":
+			errValue = json.Unmarshal([]byte(value), &record.Category)
+		case "tags":
+			errValue = json.Unmarshal([]byte(value), &record.Tags)
+		case "created_at":
+			record.CreatedAt, errValue = time.Parse(time.RFC3339Nano, value)
+		case "updated_at":
+			record.UpdatedAt, errValue = time.Parse(time.RFC3339Nano, value)
+		}
+		if errValue != nil {
+			return record, fmt.Errorf("%s is invalid", strings.TrimSpace(key))
+		}
+	}
+
+	return record, nil
+}
+
+// readTransferRecords parses an import in any of the export formats.
+func readTransferRecords(format string, data []byte) ([]web.TransferRecord, error) {
+	var records []web.TransferRecord
+	var errRead error
+	switch format {
+	case web.FormatJSONL:
+		records, errRead = readJSONL(bytes.NewReader(data), "line")
+	case web.FormatCSV:
+		records, errRead = readCSV(data)
+	case web.FormatMarkdown:
+		records, errRead = readMarkdown(data)
+	default:
+		return nil, &exception.BadRequestError{Message: "format is invalid"}
+	}
+	var errUnprocessable *exception.UnprocessableEntityError
+	if errors.As(errRead, &errUnprocessable) {
+		return nil, errRead
+	}
+	if errRead != nil {
+		return nil, &exception.BadRequestError{Message: errRead.Error()}
+	}
+
+	return records, nil
+}
+
+func readJSONL(r io.Reader, position string) ([]web.TransferRecord, error) {
+	var records []web.TransferRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record web.TransferRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s %d: %s", position, line, err.Error())
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+func readCSV(data []byte) ([]web.TransferRecord, error) {
+	rows, errRead := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if errRead != nil {
+		return nil, errRead
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	for _, name := range transferColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %s is missing", name)
+		}
+	}
+
+	var records []web.TransferRecord
+	for i, row := range rows[1:] {
+		line := i + 2
+		field := func(name string) string {
+			return row[columns[name]]
+		}
+
+		record := web.TransferRecord{
+			Type:     field("type"),
+			Name:     field("name"),
+			Title:    field("title"),
+			Body:     field("body"),
+			Format:   field("format"),
+			Category: field("category"),
+		}
+		var errField error
+		if record.ID, errField = strconv.Atoi(field("id")); errField != nil {
+			return nil, fmt.Errorf("line %d: id is invalid", line)
+		}
+		if raw := field("parent_id"); raw != "" {
+			parentID, errParent := strconv.Atoi(raw)
+			if errParent != nil {
+				return nil, fmt.Errorf("line %d: parent_id is invalid", line)
+			}
+			record.ParentID = &parentID
+		}
+		if raw := field("category_id"); raw != "" {
+			if record.CategoryID, errField = strconv.Atoi(raw); errField != nil {
+				return nil, fmt.Errorf("line %d: category_id is invalid", line)
+			}
+		}
+		if raw := field("tags"); raw != "" {
+			record.Tags = strings.Split(raw, ",")
+		}
+		if record.CreatedAt, errField = time.Parse(time.RFC3339Nano, field("created_at")); errField != nil {
+			return nil, fmt.Errorf("line %d: created_at is invalid", line)
+		}
+		if record.UpdatedAt, errField = time.Parse(time.RFC3339Nano, field("updated_at")); errField != nil {
+			return nil, fmt.Errorf("line %d: updated_at is invalid", line)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func readMarkdown(data []byte) ([]web.TransferRecord, error) {
+	archive, errOpen := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if errOpen != nil {
+		return nil, errOpen
+	}
+
+	if len(archive.File) > ImportMaxFiles {
+		return nil, &exception.UnprocessableEntityError{Message: fmt.Sprintf("archive can have at most %d files", ImportMaxFiles)}
+	}
+	errTooLarge := &exception.UnprocessableEntityError{Message: fmt.Sprintf("archive can be at most %d MB unpacked", ImportMaxSize>>20)}
+
+	// budget is what is left of ImportMaxSize for the files still to read,
+	// so an archive of many well compressed files can't take more memory
+	// than a plain import.
+	budget := int64(ImportMaxSize)
+	var categories, notes []web.TransferRecord
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		isCategories := file.Name == markdownCategories
+		if !isCategories && path.Ext(file.Name) != ".md" {
+			continue
+		}
+
+		if file.UncompressedSize64 > uint64(budget) {
+			return nil, errTooLarge
+		}
+		opened, errFile := file.Open()
+		if errFile != nil {
+			return nil, errFile
+		}
+		// The declared size can lie, so the reader stops one byte past what
+		// is left and the bytes actually read are taken out of the budget.
+		content, errFile := io.ReadAll(io.LimitReader(opened, budget+1))
+		opened.Close()
+		if errFile != nil {
+			return nil, errFile
+		}
+		budget -= int64(len(content))
+		if budget < 0 {
+			return nil, errTooLarge
+		}
+		if isCategories {
+			if categories, errFile = readJSONL(bytes.NewReader(content), markdownCategories+" line"); errFile != nil {
+				return nil, errFile
+			}
+			continue
+		}
+
+		note, errNote := unmarshalMarkdownNote(content)
+		if errNote != nil {
+			return nil, fmt.Errorf("%s: %s", file.Name, errNote.Error())
+		}
+		notes = append(notes, note)
+	}
+
+	return append(categories, notes...), nil
+}
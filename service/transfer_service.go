@@ -0,0 +1,516 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"gorm.io/gorm"
+)
+
+type TransferService interface {
+	Export(ctx context.Context, userID int, format string, w io.Writer) error
+	Import(ctx context.Context, userID int, request web.ImportRequest) (web.ImportReport, error)
+}
+
+type transferServiceImpl struct {
+	TxManager          TxManager
+	Validate           *validator.Validate
+	BodyMaxSize        int
+	NoteRepository     repository.NoteRepository
+	CategoryRepository repository.CategoryRepository
+	RevisionRepository repository.NoteRevisionRepository
+	TagRepository      repository.TagRepository
+}
+
+func NewTransferService(txManager TxManager, validate *validator.Validate, bodyMaxSize int,
+	noteRepository repository.NoteRepository, categoryRepository repository.CategoryRepository,
+	revisionRepository repository.NoteRevisionRepository, tagRepository repository.TagRepository) *transferServiceImpl {
+	return &transferServiceImpl{
+		TxManager:          txManager,
+		Validate:           validate,
+		BodyMaxSize:        bodyMaxSize,
+		NoteRepository:     noteRepository,
+		CategoryRepository: categoryRepository,
+		RevisionRepository: revisionRepository,
+		TagRepository:      tagRepository,
+	}
+}
+
+func newCategoryRecord(category domain.Category) web.TransferRecord {
+	return web.TransferRecord{
+		Type:      web.RecordCategory,
+		ID:        category.ID,
+		Name:      category.Name,
+		ParentID:  category.ParentID,
+		CreatedAt: category.CreatedAt,
+		UpdatedAt: category.UpdatedAt,
+	}
+}
+
+func newNoteRecord(note domain.ScanNote) web.TransferRecord {
+	return web.TransferRecord{
+		Type:       web.RecordNote,
+		ID:         note.ID,
+		Title:      note.Title,
+		Body:       note.Body,
+		Format:     note.Format,
+		CategoryID: note.CategoryID,
+		Category:   note.Category,
+		Tags:       splitTagNames(note.Tags),
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+	}
+}
+
+// parentsFirst orders categories so every parent comes before its children.
+// parentOf reports the parent of a category when it is part of the same list.
+func parentsFirst[T any](items []T, id func(T) int, parentOf func(T) (int, bool)) []T {
+	ordered := make([]T, 0, len(items))
+	done := make(map[int]bool, len(items))
+	for len(ordered) < len(items) {
+		added := false
+		for _, item := range items {
+			if done[id(item)] {
+				continue
+			}
+			if parentID, ok := parentOf(item); ok && !done[parentID] {
+				continue
+			}
+			ordered = append(ordered, item)
+			done[id(item)] = true
+			added = true
+		}
+		// What is left points at each other in a cycle.
+		if !added {
+			break
+		}
+	}
+	return ordered
+}
+
+func (s *transferServiceImpl) Export(ctx context.Context, userID int, format string, w io.Writer) error {
+	writer, errWriter := newTransferWriter(format, w)
+	if errWriter != nil {
+		return errWriter
+	}
+
+	// A repeatable read snapshot keeps categories and notes consistent with
+	// each other while the export streams.
+	snapshot := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	errExport := s.TxManager.Run(ctx, snapshot, func(tx *gorm.DB) error {
+		categoriesDom, errFind := s.CategoryRepository.FindTree(ctx, tx, userID)
+		if errFind != nil {
+			return errFind
+		}
+		ids := make(map[int]bool, len(categoriesDom))
+		for _, cDom := range categoriesDom {
+			ids[cDom.ID] = true
+		}
+		categoriesDom = parentsFirst(categoriesDom, func(c domain.Category) int { return c.ID },
+			func(c domain.Category) (int, bool) {
+				if c.ParentID == nil || !ids[*c.ParentID] {
+					return 0, false
+				}
+				return *c.ParentID, true
+			})
+		for _, cDom := range categoriesDom {
+			if errWrite := writer.Write(newCategoryRecord(cDom)); errWrite != nil {
+				return errWrite
+			}
+		}
+
+		return s.NoteRepository.StreamAll(ctx, tx, userID, func(note domain.ScanNote) error {
+			return writer.Write(newNoteRecord(note))
+		})
+	})
+	if errExport != nil {
+		return errExport
+	}
+
+	return writer.Close()
+}
+
+// importPlan is the outcome of the validation pass: what an import is going
+// to write, in the order it has to be written.
+type importPlan struct {
+	categories []importItem[domain.Category]
+	notes      []importItem[domain.Note]
+}
+
+// importItem is a record of the import. existing is set when the record
+// overwrites a stored row with the same id.
+type importItem[T any] struct {
+	record   web.TransferRecord
+	tags     []string
+	existing *T
+}
+
+func (s *transferServiceImpl) Import(ctx context.Context, userID int, request web.ImportRequest) (web.ImportReport, error) {
+	if request.IDs == "" {
+		request.IDs = web.ImportRemapIDs
+	}
+	report := web.ImportReport{DryRun: request.DryRun, IDs: request.IDs, Conflicts: []web.ImportConflict{}}
+	if request.IDs != web.ImportRemapIDs && request.IDs != web.ImportPreserveIDs {
+		return report, &exception.BadRequestError{Message: "ids is invalid"}
+	}
+
+	records, errRead := readTransferRecords(request.Format, request.Data)
+	if errRead != nil {
+		return report, errRead
+	}
+
+	// A dry run only plans, and rolls back whatever planning touched.
+	errTx := s.TxManager.Do(ctx, func(tx *gorm.DB) error {
+		plan, errPlan := s.planImport(ctx, tx, userID, request.IDs, records, &report)
+		if errPlan != nil {
+			return errPlan
+		}
+		if request.DryRun {
+			return ErrRollback
+		}
+		if len(report.Conflicts) > 0 {
+			return &exception.ConflictError{Message: "import has conflicts", Details: report}
+		}
+
+		return s.applyImport(ctx, tx, userID, request.IDs, plan)
+	})
+	if errTx != nil {
+		return report, errTx
+	}
+
+	return report, nil
+}
+
+// planImport is the validation pass of an import. It writes nothing and adds
+// every record that can't be imported to the conflicts of the report.
+func (s *transferServiceImpl) planImport(ctx context.Context, tx *gorm.DB, userID int, ids string, records []web.TransferRecord,
+	report *web.ImportReport) (importPlan, error) {
+	var plan importPlan
+	preserve := ids == web.ImportPreserveIDs
+	conflict := func(record web.TransferRecord, message string) {
+		report.Conflicts = append(report.Conflicts, web.ImportConflict{Type: record.Type, ID: record.ID, Message: message})
+	}
+
+	var categoryRecords, noteRecords []web.TransferRecord
+	var categoryIDs, noteIDs []int
+	for _, record := range records {
+		switch record.Type {
+		case web.RecordCategory:
+			categoryRecords = append(categoryRecords, record)
+			categoryIDs = append(categoryIDs, record.ID)
+		case web.RecordNote:
+			noteRecords = append(noteRecords, record)
+			noteIDs = append(noteIDs, record.ID)
+		default:
+			conflict(record, fmt.Sprintf("record type %q is invalid", record.Type))
+		}
+	}
+
+	userCategories, errFind := s.CategoryRepository.FindTree(ctx, tx, userID)
+	if errFind != nil {
+		return plan, errFind
+	}
+	stored := make(map[int]domain.Category, len(userCategories))
+	for _, cDom := range userCategories {
+		stored[cDom.ID] = cDom
+	}
+
+	existingCategories := map[int]domain.Category{}
+	existingNotes := map[int]domain.Note{}
+	if preserve {
+		categoriesDom, errFind := s.CategoryRepository.FindExisting(ctx, tx, categoryIDs)
+		if errFind != nil {
+			return plan, errFind
+		}
+		for _, cDom := range categoriesDom {
+			existingCategories[cDom.ID] = cDom
+		}
+		notesDom, errFind := s.NoteRepository.FindExisting(ctx, tx, noteIDs)
+		if errFind != nil {
+			return plan, errFind
+		}
+		for _, nDom := range notesDom {
+			existingNotes[nDom.ID] = nDom
+		}
+	}
+
+	// Categories of the file, by their id in the file.
+	inFile := make(map[int]web.TransferRecord, len(categoryRecords))
+	for _, record := range categoryRecords {
+		if _, ok := inFile[record.ID]; ok {
+			conflict(record, "category id appears more than once")
+			continue
+		}
+		if errValidate := s.Validate.Struct(web.CategoryJSON{Name: record.Name}); errValidate != nil {
+			conflict(record, exception.NewErrorResponse(errValidate).Message)
+			continue
+		}
+		if record.ParentID != nil {
+			if _, ok := stored[*record.ParentID]; !ok && !slices.Contains(categoryIDs, *record.ParentID) {
+				conflict(record, fmt.Sprintf("parent category %d does not exist", *record.ParentID))
+				continue
+			}
+		}
+
+		item := importItem[domain.Category]{record: record}
+		if existing, ok := existingCategories[record.ID]; ok {
+			if existing.UserID != userID {
+				conflict(record, "category id belongs to another user")
+				continue
+			}
+			if existing.DeletedAt.Valid {
+				conflict(record, "category is in the trash")
+				continue
+			}
+			item.existing = &existing
+		}
+		inFile[record.ID] = record
+		plan.categories = append(plan.categories, item)
+	}
+
+	// A parent outside of the file keeps its stored parent, which only matters
+	// when ids are preserved, since new categories can't be the parent of a
+	// stored one.
+	parentOf := func(id int) (int, bool) {
+		if record, ok := inFile[id]; ok {
+			if record.ParentID == nil {
+				return 0, false
+			}
+			return *record.ParentID, true
+		}
+		if category, ok := stored[id]; ok && preserve && category.ParentID != nil {
+			return *category.ParentID, true
+		}
+		return 0, false
+	}
+	acyclic := plan.categories[:0]
+	for _, item := range plan.categories {
+		seen := map[int]bool{item.record.ID: true}
+		isCycle := false
+		for id, ok := parentOf(item.record.ID); ok; id, ok = parentOf(id) {
+			if seen[id] {
+				isCycle = true
+				break
+			}
+			seen[id] = true
+		}
+		if isCycle {
+			conflict(item.record, "category would become its own ancestor")
+			delete(inFile, item.record.ID)
+			continue
+		}
+		acyclic = append(acyclic, item)
+	}
+	// Children of a category that can't be imported can't be imported either.
+	for isDropped := true; isDropped; {
+		isDropped = false
+		kept := acyclic[:0]
+		for _, item := range acyclic {
+			if parentID := item.record.ParentID; parentID != nil {
+				_, isStored := stored[*parentID]
+				if _, ok := inFile[*parentID]; !ok && (slices.Contains(categoryIDs, *parentID) || !isStored) {
+					conflict(item.record, fmt.Sprintf("parent category %d can not be imported", *parentID))
+					delete(inFile, item.record.ID)
+					isDropped = true
+					continue
+				}
+			}
+			kept = append(kept, item)
+		}
+		acyclic = kept
+	}
+	plan.categories = parentsFirst(acyclic, func(item importItem[domain.Category]) int { return item.record.ID },
+		func(item importItem[domain.Category]) (int, bool) {
+			if item.record.ParentID == nil {
+				return 0, false
+			}
+			_, ok := inFile[*item.record.ParentID]
+			return *item.record.ParentID, ok
+		})
+
+	seenNotes := make(map[int]bool, len(noteRecords))
+	for _, record := range noteRecords {
+		if seenNotes[record.ID] {
+			conflict(record, "note id appears more than once")
+			continue
+		}
+		seenNotes[record.ID] = true
+
+		if _, ok := inFile[record.CategoryID]; !ok {
+			if slices.Contains(categoryIDs, record.CategoryID) {
+				conflict(record, fmt.Sprintf("category %d can not be imported", record.CategoryID))
+				continue
+			}
+			if _, ok := stored[record.CategoryID]; !ok {
+				conflict(record, fmt.Sprintf("category %d does not exist", record.CategoryID))
+				continue
+			}
+		}
+		if errValidate := validateNote(ctx, s.Validate, web.NoteRequest{
+			Title:      record.Title,
+			Body:       record.Body,
+			Format:     record.Format,
+			CategoryId: record.CategoryID,
+			Tags:       record.Tags,
+		}, s.BodyMaxSize); errValidate != nil {
+			conflict(record, exception.NewErrorResponse(errValidate).Message)
+			continue
+		}
+		tags, errTags := NormalizeTagNames(record.Tags)
+		if errTags != nil {
+			conflict(record, errTags.Error())
+			continue
+		}
+
+		item := importItem[domain.Note]{record: record, tags: tags}
+		if existing, ok := existingNotes[record.ID]; ok {
+			if existing.UserID != userID {
+				conflict(record, "note id belongs to another user")
+				continue
+			}
+			if existing.DeletedAt.Valid {
+				conflict(record, "note is in the trash")
+				continue
+			}
+			item.existing = &existing
+		}
+		plan.notes = append(plan.notes, item)
+	}
+
+	for _, item := range plan.categories {
+		if item.existing != nil {
+			report.Categories.Updated++
+		} else {
+			report.Categories.Created++
+		}
+	}
+	for _, item := range plan.notes {
+		if item.existing != nil {
+			report.Notes.Updated++
+		} else {
+			report.Notes.Created++
+		}
+	}
+
+	return plan, nil
+}
+
+func (s *transferServiceImpl) applyImport(ctx context.Context, tx *gorm.DB, userID int, ids string, plan importPlan) error {
+	preserve := ids == web.ImportPreserveIDs
+
+	// categoryIDs maps the ids of the file to the stored ids.
+	categoryIDs := make(map[int]int, len(plan.categories))
+	mapCategory := func(id int) int {
+		if storedID, ok := categoryIDs[id]; ok {
+			return storedID
+		}
+		return id
+	}
+
+	for _, item := range plan.categories {
+		var parentID *int
+		if item.record.ParentID != nil {
+			mapped := mapCategory(*item.record.ParentID)
+			parentID = &mapped
+		}
+
+		if item.existing != nil {
+			categoryDom := *item.existing
+			categoryDom.Name = item.record.Name
+			categoryDom.ParentID = parentID
+			if _, errSave := s.CategoryRepository.Save(ctx, tx, categoryDom); errSave != nil {
+				return translateVersionError("category", errSave)
+			}
+			categoryIDs[item.record.ID] = categoryDom.ID
+			continue
+		}
+
+		categoryDom := domain.Category{
+			Name:      item.record.Name,
+			ParentID:  parentID,
+			UserID:    userID,
+			CreatedAt: item.record.CreatedAt,
+			UpdatedAt: item.record.UpdatedAt,
+		}
+		if preserve {
+			categoryDom.ID = item.record.ID
+		}
+		categoryDom, errSave := s.CategoryRepository.Insert(ctx, tx, categoryDom)
+		if errSave != nil {
+			return errSave
+		}
+		categoryIDs[item.record.ID] = categoryDom.ID
+	}
+
+	var newNotes []domain.Note
+	var newTags [][]string
+	for _, item := range plan.notes {
+		noteDom := domain.Note{
+			Title:      item.record.Title,
+			Body:       item.record.Body,
+			Format:     noteFormat(item.record.Format, web.NoteFormatPlain),
+			CategoryID: mapCategory(item.record.CategoryID),
+			UserID:     userID,
+			CreatedAt:  item.record.CreatedAt,
+			UpdatedAt:  item.record.UpdatedAt,
+		}
+		if item.existing == nil {
+			if preserve {
+				noteDom.ID = item.record.ID
+			}
+			newNotes = append(newNotes, noteDom)
+			newTags = append(newTags, item.tags)
+			continue
+		}
+
+		noteDom.ID = item.existing.ID
+		noteDom.Version = item.existing.Version
+		noteDom.Format = noteFormat(item.record.Format, item.existing.Format)
+		if noteDom.CreatedAt.IsZero() {
+			noteDom.CreatedAt = item.existing.CreatedAt
+		}
+		noteDom, errSave := s.NoteRepository.Save(ctx, tx, noteDom)
+		if errSave != nil {
+			return translateVersionError("note", errSave)
+		}
+		if _, errSave := s.RevisionRepository.Save(ctx, tx, newNoteRevision(noteDom, userID)); errSave != nil {
+			return errSave
+		}
+		tagsDom, errFind := s.TagRepository.FindOrCreate(ctx, tx, userID, item.tags)
+		if errFind != nil {
+			return errFind
+		}
+		tagIDs := make([]int, 0, len(tagsDom))
+		for _, tDom := range tagsDom {
+			tagIDs = append(tagIDs, tDom.ID)
+		}
+		if errReplace := s.NoteRepository.ReplaceTags(ctx, tx, noteDom.ID, tagIDs); errReplace != nil {
+			return errReplace
+		}
+	}
+	if len(newNotes) > 0 {
+		if _, errSave := insertNotes(ctx, tx, s.NoteRepository, s.RevisionRepository, s.TagRepository,
+			userID, newNotes, newTags); errSave != nil {
+			return errSave
+		}
+	}
+
+	if preserve {
+		if errSync := s.CategoryRepository.SyncIdSequence(ctx, tx); errSync != nil {
+			return errSync
+		}
+		if errSync := s.NoteRepository.SyncIdSequence(ctx, tx); errSync != nil {
+			return errSync
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"gorm.io/gorm"
+)
+
+type validationUserKey struct{}
+
+// withValidationUser returns the context to validate a request of userID with,
+// so the category_exists tag knows whose categories to look at.
+func withValidationUser(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, validationUserKey{}, userID)
+}
+
+// RegisterValidations adds the validate tags that look at stored data.
+// category_exists fails when the user of the validation context has no live
+// category with that id. Without a user it is skipped, which batches and
+// imports rely on as they look all their categories up at once.
+func RegisterValidations(validate *validator.Validate, txManager TxManager, categoryRepository repository.CategoryRepository) error {
+	errRegister := validate.RegisterValidationCtx("category_exists", func(ctx context.Context, fl validator.FieldLevel) bool {
+		userID, ok := ctx.Value(validationUserKey{}).(int)
+		if !ok {
+			return true
+		}
+		if !fl.Field().CanInt() {
+			return false
+		}
+
+		exists := false
+		txManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+			exists = categoryRepository.IsExistById(ctx, tx, userID, int(fl.Field().Int()))
+			return nil
+		})
+		return exists
+	})
+	if errRegister != nil {
+		return errRegister
+	}
+
+	return helper.RegisterTranslation(validate, "category_exists", map[string]string{
+		"en": "{0} must be an existing category",
+		"id": "{0} harus berupa kategori yang sudah ada",
+	})
+}
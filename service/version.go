@@ -0,0 +1,31 @@
+package service
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/repository"
+)
+
+func staleVersionError(entity string) error {
+	return &exception.PreconditionFailedError{Message: entity + " has been modified since it was read"}
+}
+
+// checkVersion compares the versions a change may be based on with the stored
+// one. No expected versions, sent as If-Match: *, accepts any version.
+func checkVersion(entity string, expected []int, current int) error {
+	if len(expected) > 0 && !slices.Contains(expected, current) {
+		return staleVersionError(entity)
+	}
+	return nil
+}
+
+// translateVersionError turns a write lost to a concurrent update into the
+// same error as a stale If-Match.
+func translateVersionError(entity string, err error) error {
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return staleVersionError(entity)
+	}
+	return err
+}
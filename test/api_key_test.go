@@ -0,0 +1,187 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+type testAPIKeyJSON struct {
+	Code   int                `json:"code"`
+	Status string             `json:"status"`
+	Data   web.APIKeyResponse `json:"data"`
+}
+
+type testAPIKeyListJSON struct {
+	Code   int                  `json:"code"`
+	Status string               `json:"status"`
+	Data   []web.APIKeyResponse `json:"data"`
+}
+
+var apiKeyUrl string = "http://127.0.0.1:8000/api/keys"
+
+func deleteAPIKeyRecords() {
+	db.Where("1=1").Delete(&domain.APIKey{})
+}
+
+func createTestAPIKey(requestBody string) web.APIKeyResponse {
+	request := newTestRequest(apiKeyUrl, http.MethodPost, requestBody)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+
+	responseBody, _ := io.ReadAll(recorder.Result().Body)
+	var responseCreate testAPIKeyJSON
+	json.Unmarshal(responseBody, &responseCreate)
+
+	return responseCreate.Data
+}
+
+func newAPIKeyRequest(url string, method string, requestBody string, key string) *http.Request {
+	request := newAnonymousRequest(url, method, requestBody)
+	request.Header.Add("X-API-Key", key)
+
+	return request
+}
+
+func TestAPIKeys(t *testing.T) {
+	defer deleteAPIKeyRecords()
+	defer database.DeleteAllRecords(db)
+	categories := database.CategorySeeder(db, testUser.ID, 1)
+
+	t.Run("APIKey_Create_Success", func(t *testing.T) {
+		apiKey := createTestAPIKey(`{"name": "reporting", "scopes": ["notes:read"]}`)
+
+		require.NotEmpty(t, apiKey.Key)
+		require.Equal(t, apiKey.Key[:len(apiKey.Prefix)], apiKey.Prefix)
+		require.Equal(t, []string{domain.ScopeNotesRead}, apiKey.Scopes)
+	})
+	t.Run("APIKey_Create_InvalidScope_Fail", func(t *testing.T) {
+		request := newTestRequest(apiKeyUrl, http.MethodPost, `{"name": "bad", "scopes": ["notes:admin"]}`)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseCreate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseCreate)
+
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "scopes[0]", responseCreate.Errors[0].Field)
+		require.Equal(t, "oneof", responseCreate.Errors[0].Rule)
+	})
+	t.Run("APIKey_GetAll_Success", func(t *testing.T) {
+		request := newTestRequest(apiKeyUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testAPIKeyListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.NotEmpty(t, responseGet.Data)
+		require.NotContains(t, string(responseBody), `"key"`)
+	})
+	t.Run("APIKey_Header_Read_Success", func(t *testing.T) {
+		apiKey := createTestAPIKey(`{"name": "reader", "scopes": ["notes:read"]}`)
+		request := newAPIKeyRequest(noteUrl, http.MethodGet, "", apiKey.Key)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+	t.Run("APIKey_Bearer_Read_Success", func(t *testing.T) {
+		apiKey := createTestAPIKey(`{"name": "reader", "scopes": ["notes:read"]}`)
+		request := newAnonymousRequest(noteUrl, http.MethodGet, "")
+		request.Header.Add("Authorization", "Bearer "+apiKey.Key)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+	t.Run("APIKey_Missing_Scope_Fail", func(t *testing.T) {
+		apiKey := createTestAPIKey(`{"name": "reader", "scopes": ["notes:read"]}`)
+		requestBody := `{"title": "Title", "body": "Body", "id_category": ` + strconv.Itoa(categories[0].ID) + `}`
+		request := newAPIKeyRequest(noteUrl, http.MethodPost, requestBody, apiKey.Key)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseCreate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseCreate)
+
+		require.Equal(t, http.StatusForbidden, responseCreate.Code)
+		require.Equal(t, "api key is missing scope notes:write", responseCreate.Message)
+	})
+	t.Run("APIKey_Manage_Keys_Fail", func(t *testing.T) {
+		apiKey := createTestAPIKey(`{"name": "writer", "scopes": ["notes:write"]}`)
+		request := newAnonymousRequest(apiKeyUrl, http.MethodGet, "")
+		request.Header.Add("Authorization", "Bearer "+apiKey.Key)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+	t.Run("APIKey_Rotate_Success", func(t *testing.T) {
+		apiKey := createTestAPIKey(`{"name": "rotated", "scopes": ["notes:read"]}`)
+		request := newTestRequest(apiKeyUrl+"/"+strconv.Itoa(apiKey.ID)+"/rotate", http.MethodPost, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRotate testAPIKeyJSON
+		json.Unmarshal(responseBody, &responseRotate)
+
+		require.Equal(t, http.StatusOK, responseRotate.Code)
+		require.NotEqual(t, apiKey.Key, responseRotate.Data.Key)
+
+		recorder = httptest.NewRecorder()
+		e.ServeHTTP(recorder, newAPIKeyRequest(noteUrl, http.MethodGet, "", apiKey.Key))
+		require.Equal(t, http.StatusUnauthorized, recorder.Result().StatusCode)
+
+		recorder = httptest.NewRecorder()
+		e.ServeHTTP(recorder, newAPIKeyRequest(noteUrl, http.MethodGet, "", responseRotate.Data.Key))
+		require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	})
+	t.Run("APIKey_Revoke_Success", func(t *testing.T) {
+		apiKey := createTestAPIKey(`{"name": "revoked", "scopes": ["notes:read"]}`)
+		request := newTestRequest(apiKeyUrl+"/"+strconv.Itoa(apiKey.ID), http.MethodDelete, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+		recorder = httptest.NewRecorder()
+		e.ServeHTTP(recorder, newAPIKeyRequest(noteUrl, http.MethodGet, "", apiKey.Key))
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusUnauthorized, responseGet.Code)
+		require.Equal(t, "api key is invalid or revoked", responseGet.Message)
+	})
+}
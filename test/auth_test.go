@@ -0,0 +1,296 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+type testUserJSON struct {
+	Code   int              `json:"code"`
+	Status string           `json:"status"`
+	Data   web.UserResponse `json:"data"`
+}
+
+var authUrl string = "http://127.0.0.1:8000/api/auth"
+
+func deleteAuthTestUsers() {
+	db.Where("email LIKE ?", "auth-%").Delete(&domain.User{})
+}
+
+func newAnonymousRequest(url string, method string, requestBody string) *http.Request {
+	request := httptest.NewRequest(method, url, strings.NewReader(requestBody))
+	request.Header.Add("Content-Type", "application/json")
+
+	return request
+}
+
+func TestRegister(t *testing.T) {
+	defer deleteAuthTestUsers()
+	email := "auth-" + strings.ToLower(helper.RandomString(10)) + "@example.com"
+
+	t.Run("Auth_Register_Success", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"email": "%s", "password": "secret-password"}`, email)
+		request := newAnonymousRequest(authUrl+"/register", http.MethodPost, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRegister testUserJSON
+		json.Unmarshal(responseBody, &responseRegister)
+
+		require.Equal(t, http.StatusOK, responseRegister.Code)
+		require.Equal(t, "OK", responseRegister.Status)
+		require.Equal(t, email, responseRegister.Data.Email)
+		require.Equal(t, domain.RoleEditor, responseRegister.Data.Role)
+		require.NotContains(t, string(responseBody), "secret-password")
+	})
+	t.Run("Auth_Register_Duplicate_Fail", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"email": "%s", "password": "secret-password"}`, email)
+		request := newAnonymousRequest(authUrl+"/register", http.MethodPost, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRegister web.ErrorResponse
+		json.Unmarshal(responseBody, &responseRegister)
+
+		require.Equal(t, http.StatusBadRequest, responseRegister.Code)
+		require.Equal(t, "email already registered", responseRegister.Message)
+	})
+	t.Run("Auth_Register_Validation_Fail", func(t *testing.T) {
+		requestBody := `{"email": "auth-short@example.com", "password": "short"}`
+		request := newAnonymousRequest(authUrl+"/register", http.MethodPost, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRegister web.ErrorResponse
+		json.Unmarshal(responseBody, &responseRegister)
+
+		require.Equal(t, http.StatusUnprocessableEntity, responseRegister.Code)
+		require.Equal(t, "UNPROCESSABLE ENTITY", responseRegister.Status)
+		require.Equal(t, []web.FieldError{{
+			Field:   "password",
+			Rule:    "min",
+			Param:   "8",
+			Message: "password must be at least 8 characters in length",
+		}}, responseRegister.Errors)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	t.Run("Auth_Login_Success", func(t *testing.T) {
+		tokens := loginTestUser(testUser.Email, testUserPassword)
+
+		require.NotEmpty(t, tokens.AccessToken)
+		require.NotEmpty(t, tokens.RefreshToken)
+		require.Equal(t, "Bearer", tokens.TokenType)
+	})
+	t.Run("Auth_Login_WrongPassword_Fail", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"email": "%s", "password": "wrong-password"}`, testUser.Email)
+		request := newAnonymousRequest(authUrl+"/login", http.MethodPost, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseLogin web.ErrorResponse
+		json.Unmarshal(responseBody, &responseLogin)
+
+		require.Equal(t, http.StatusUnauthorized, responseLogin.Code)
+		require.Equal(t, "UNAUTHORIZED", responseLogin.Status)
+		require.Equal(t, "invalid email or password", responseLogin.Message)
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	tokens := loginTestUser(testUser.Email, testUserPassword)
+
+	t.Run("Auth_Refresh_Success", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"refresh_token": "%s"}`, tokens.RefreshToken)
+		request := newAnonymousRequest(authUrl+"/refresh", http.MethodPost, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRefresh testTokenJSON
+		json.Unmarshal(responseBody, &responseRefresh)
+
+		require.Equal(t, http.StatusOK, responseRefresh.Code)
+		require.NotEmpty(t, responseRefresh.Data.AccessToken)
+	})
+	t.Run("Auth_Refresh_WithAccessToken_Fail", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"refresh_token": "%s"}`, tokens.AccessToken)
+		request := newAnonymousRequest(authUrl+"/refresh", http.MethodPost, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRefresh web.ErrorResponse
+		json.Unmarshal(responseBody, &responseRefresh)
+
+		require.Equal(t, http.StatusUnauthorized, responseRefresh.Code)
+	})
+}
+
+func TestOwnership(t *testing.T) {
+	defer deleteAuthTestUsers()
+	defer database.DeleteAllRecords(db)
+	categories := database.CategorySeeder(db, testUser.ID, 1)
+	notes := database.NoteSeeder(db, categories, 1)
+	otherUser := database.UserSeeder(db, "auth-other@example.com", "other-password", domain.RoleAdmin)
+	otherToken := loginTestUser(otherUser.Email, "other-password").AccessToken
+
+	t.Run("Auth_Anonymous_Fail", func(t *testing.T) {
+		request := newAnonymousRequest(noteUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusUnauthorized, responseGet.Code)
+		require.Equal(t, "missing bearer token", responseGet.Message)
+	})
+	t.Run("Auth_Other_User_Note_NotFound_Fail", func(t *testing.T) {
+		request := newAnonymousRequest(noteUrl+"/"+strconv.Itoa(notes[0].ID), http.MethodGet, "")
+		request.Header.Add("Authorization", "Bearer "+otherToken)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusNotFound, responseGet.Code)
+		require.Equal(t, "note not found", responseGet.Message)
+	})
+	t.Run("Auth_Admin_Other_User_Category_Delete_Restrict_Fail", func(t *testing.T) {
+		request := newAnonymousRequest(categoryUrl+"/"+strconv.Itoa(categories[0].ID), http.MethodDelete, "")
+		request.Header.Add("Authorization", "Bearer "+otherToken)
+		request.Header.Add("If-Match", "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseDelete web.ErrorResponse
+		json.Unmarshal(responseBody, &responseDelete)
+
+		require.Equal(t, http.StatusConflict, responseDelete.Code)
+		require.Equal(t, "category still has notes", responseDelete.Message)
+	})
+	t.Run("Auth_Admin_Other_User_Category_Delete_Success", func(t *testing.T) {
+		category := database.CategorySeeder(db, testUser.ID, 1)[0]
+		request := newAnonymousRequest(categoryUrl+"/"+strconv.Itoa(category.ID), http.MethodDelete, "")
+		request.Header.Add("Authorization", "Bearer "+otherToken)
+		request.Header.Add("If-Match", "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		recorder = httptest.NewRecorder()
+		e.ServeHTTP(recorder, newTestRequest(categoryUrl+"/"+strconv.Itoa(category.ID), http.MethodGet, ""))
+		require.Equal(t, http.StatusNotFound, recorder.Result().StatusCode)
+	})
+	t.Run("Auth_Other_User_List_Empty_Success", func(t *testing.T) {
+		request := newAnonymousRequest(noteUrl, http.MethodGet, "")
+		request.Header.Add("Authorization", "Bearer "+otherToken)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testNoteListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 0, len(responseGet.Data))
+	})
+}
+
+func TestRoles(t *testing.T) {
+	defer deleteAuthTestUsers()
+	defer database.DeleteAllRecords(db)
+	editor := database.UserSeeder(db, "auth-editor@example.com", "editor-password", domain.RoleEditor)
+	viewer := database.UserSeeder(db, "auth-viewer@example.com", "viewer-password", domain.RoleViewer)
+	editorToken := loginTestUser(editor.Email, "editor-password").AccessToken
+	viewerToken := loginTestUser(viewer.Email, "viewer-password").AccessToken
+	categories := database.CategorySeeder(db, editor.ID, 1)
+
+	t.Run("Role_Editor_Delete_Category_Forbidden_Fail", func(t *testing.T) {
+		request := newAnonymousRequest(categoryUrl+"/"+strconv.Itoa(categories[0].ID), http.MethodDelete, "")
+		request.Header.Add("Authorization", "Bearer "+editorToken)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseDelete web.ErrorResponse
+		json.Unmarshal(responseBody, &responseDelete)
+
+		require.Equal(t, http.StatusForbidden, responseDelete.Code)
+		require.Equal(t, "FORBIDDEN", responseDelete.Status)
+		require.Equal(t, "requires admin role", responseDelete.Message)
+	})
+	t.Run("Role_Viewer_Create_Note_Forbidden_Fail", func(t *testing.T) {
+		requestBody := `{"title": "Title", "body": "Body", "id_category": 1}`
+		request := newAnonymousRequest(noteUrl, http.MethodPost, requestBody)
+		request.Header.Add("Authorization", "Bearer "+viewerToken)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseCreate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseCreate)
+
+		require.Equal(t, http.StatusForbidden, responseCreate.Code)
+		require.Equal(t, "requires editor role", responseCreate.Message)
+	})
+	t.Run("Role_Viewer_List_Notes_Success", func(t *testing.T) {
+		request := newAnonymousRequest(noteUrl, http.MethodGet, "")
+		request.Header.Add("Authorization", "Bearer "+viewerToken)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+}
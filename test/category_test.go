@@ -12,6 +12,7 @@ import (
 
 	"github.com/naomigrain/echo-crud-notes/app/database"
 	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"github.com/naomigrain/echo-crud-notes/model/web"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -27,6 +28,14 @@ type testCategoryListJSON struct {
 	Code   int                `json:"code"`
 	Status string             `json:"status"`
 	Data   []web.CategoryJSON `json:"data"`
+	Meta   web.PageMeta       `json:"meta"`
+}
+
+type testCategoryCursorJSON struct {
+	Code   int                `json:"code"`
+	Status string             `json:"status"`
+	Data   []web.CategoryJSON `json:"data"`
+	Meta   web.CursorMeta     `json:"meta"`
 }
 
 var categoryUrl string = "http://127.0.0.1:8000/api/categories"
@@ -68,22 +77,23 @@ func TestCreateCategory(t *testing.T) {
 		var responseCreate web.ErrorResponse
 		json.Unmarshal(responseBody, &responseCreate)
 
-		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
-		require.Equal(t, "BAD REQUEST", responseCreate.Status)
-		require.Equal(t, "Name is should more than 2 characters", responseCreate.Message)
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "UNPROCESSABLE ENTITY", responseCreate.Status)
+		require.Equal(t, "name must be at least 2 characters in length", responseCreate.Message)
+		require.Equal(t, "name", responseCreate.Errors[0].Field)
 	})
 }
 
 func TestUpdateCategory(t *testing.T) {
 	defer database.DeleteCategoryRecords(db)
-	categories := database.CategorySeeder(db, 2)
+	categories := database.CategorySeeder(db, testUser.ID, 2)
 	t.Run("Category_Update_Success", func(t *testing.T) {
 		updateUrl := categoryUrl + "/" + strconv.Itoa(categories[0].ID)
 		categoryNameUpdate := "Category " + helper.RandomString(rand.Intn(80))
 		requestBody := fmt.Sprintf(
 			`{"name": "%s"}`, categoryNameUpdate,
 		)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -104,7 +114,7 @@ func TestUpdateCategory(t *testing.T) {
 		requestBody := fmt.Sprintf(
 			`{"name": "%s"}`, categoryNameUpdate,
 		)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -114,9 +124,9 @@ func TestUpdateCategory(t *testing.T) {
 		var responseUpdate web.ErrorResponse
 		json.Unmarshal(responseBody, &responseUpdate)
 
-		require.Equal(t, http.StatusBadRequest, responseUpdate.Code)
-		require.Equal(t, "BAD REQUEST", responseUpdate.Status)
-		require.Equal(t, "Name is should more than 2 characters", responseUpdate.Message)
+		require.Equal(t, http.StatusUnprocessableEntity, responseUpdate.Code)
+		require.Equal(t, "UNPROCESSABLE ENTITY", responseUpdate.Status)
+		require.Equal(t, "name must be at least 2 characters in length", responseUpdate.Message)
 	})
 	t.Run("Category_Update_NotFound_Fail", func(t *testing.T) {
 		updateUrl := categoryUrl + "/" + strconv.Itoa(9999999)
@@ -124,7 +134,7 @@ func TestUpdateCategory(t *testing.T) {
 		requestBody := fmt.Sprintf(
 			`{"name": "%s"}`, categoryNameUpdate,
 		)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -144,7 +154,7 @@ func TestUpdateCategory(t *testing.T) {
 		requestBody := fmt.Sprintf(
 			`{"name": "%s"}`, categoryNameUpdate,
 		)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -162,11 +172,11 @@ func TestUpdateCategory(t *testing.T) {
 
 func TestDeleteCategory(t *testing.T) {
 	defer database.DeleteCategoryRecords(db)
-	categories := database.CategorySeeder(db, 1)
+	categories := database.CategorySeeder(db, testUser.ID, 1)
 
 	t.Run("Category_Delete_Success", func(t *testing.T) {
 		deleteUrl := categoryUrl + "/" + strconv.Itoa(categories[0].ID)
-		request := newTestRequest(deleteUrl, http.MethodDelete, "")
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -181,7 +191,7 @@ func TestDeleteCategory(t *testing.T) {
 	})
 	t.Run("Category_Delete_NotFound_Fail", func(t *testing.T) {
 		deleteUrl := categoryUrl + "/" + strconv.Itoa(9999999)
-		request := newTestRequest(deleteUrl, http.MethodDelete, "")
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -195,9 +205,29 @@ func TestDeleteCategory(t *testing.T) {
 		require.Equal(t, "NOT FOUND", responseDelete.Status)
 		require.Equal(t, "category not found", responseDelete.Message)
 	})
+	t.Run("Category_Delete_HasNotes_Fail", func(t *testing.T) {
+		defer database.DeleteNoteRecords(db)
+		withNotes := database.CategorySeeder(db, testUser.ID, 1)
+		database.NoteSeeder(db, withNotes, 1)
+		deleteUrl := categoryUrl + "/" + strconv.Itoa(withNotes[0].ID)
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseDelete web.ErrorResponse
+		json.Unmarshal(responseBody, &responseDelete)
+
+		require.Equal(t, http.StatusConflict, responseDelete.Code)
+		require.Equal(t, "CONFLICT", responseDelete.Status)
+		require.Equal(t, "category still has notes", responseDelete.Message)
+		require.Equal(t, float64(1), responseDelete.Details.(map[string]interface{})["notes"])
+	})
 	t.Run("Category_Delete_NotFound2_Fail", func(t *testing.T) {
 		deleteUrl := categoryUrl + "/thisistestforid"
-		request := newTestRequest(deleteUrl, http.MethodDelete, "")
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -215,7 +245,7 @@ func TestDeleteCategory(t *testing.T) {
 
 func TestGetCategories(t *testing.T) {
 	defer database.DeleteCategoryRecords(db)
-	categories := database.CategorySeeder(db, 3)
+	categories := database.CategorySeeder(db, testUser.ID, 3)
 
 	t.Run("Category_FindById_Success", func(t *testing.T) {
 		getByIdUrl := categoryUrl + "/" + strconv.Itoa(categories[0].ID)
@@ -280,11 +310,179 @@ func TestGetCategories(t *testing.T) {
 		require.Equal(t, http.StatusOK, responseGetAll.Code)
 		require.Equal(t, "OK", responseGetAll.Status)
 		require.Equal(t, len(categories), len(responseGetAll.Data))
+		require.Equal(t, int64(len(categories)), responseGetAll.Meta.TotalItems)
 		for i := 0; i < len(categories); i++ {
 			assert.Equal(t, categories[i].ID, responseGetAll.Data[i].ID)
 			assert.Equal(t, categories[i].Name, responseGetAll.Data[i].Name)
 		}
 	})
+	t.Run("Category_GetAll_MaxPageSize_Success", func(t *testing.T) {
+		request := newTestRequest(categoryUrl+"?pageSize=100000", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGetAll testCategoryListJSON
+		json.Unmarshal(responseBody, &responseGetAll)
+
+		require.Equal(t, http.StatusOK, responseGetAll.Code)
+		require.Equal(t, helper.MaxPageSize, responseGetAll.Meta.PageSize)
+	})
+	t.Run("Category_GetAll_Sort_Success", func(t *testing.T) {
+		request := newTestRequest(categoryUrl+"?sort=-id", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGetAll testCategoryListJSON
+		json.Unmarshal(responseBody, &responseGetAll)
+
+		require.Equal(t, http.StatusOK, responseGetAll.Code)
+		require.Equal(t, len(categories), len(responseGetAll.Data))
+		for i := 0; i < len(categories); i++ {
+			assert.Equal(t, categories[len(categories)-1-i].ID, responseGetAll.Data[i].ID)
+		}
+	})
+	t.Run("Category_GetAll_Cursor_Success", func(t *testing.T) {
+		var ids []int
+		cursor := ""
+		for {
+			request := newTestRequest(categoryUrl+"?sort=-id&pageSize=2&cursor="+cursor, http.MethodGet, "")
+
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, request)
+			response := recorder.Result()
+
+			responseBody, _ := io.ReadAll(response.Body)
+			var responseGetAll testCategoryCursorJSON
+			json.Unmarshal(responseBody, &responseGetAll)
+
+			require.Equal(t, http.StatusOK, responseGetAll.Code)
+			for _, c := range responseGetAll.Data {
+				ids = append(ids, c.ID)
+			}
+			if responseGetAll.Meta.NextCursor == "" {
+				break
+			}
+			cursor = responseGetAll.Meta.NextCursor
+		}
+
+		require.Equal(t, len(categories), len(ids))
+		for i := 0; i < len(categories); i++ {
+			assert.Equal(t, categories[len(categories)-1-i].ID, ids[i])
+		}
+	})
+	t.Run("Category_GetAll_Cursor_Fail", func(t *testing.T) {
+		request := newTestRequest(categoryUrl+"?cursor=forged.token", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGetAll web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGetAll)
+
+		require.Equal(t, http.StatusBadRequest, responseGetAll.Code)
+		require.Equal(t, "cursor is invalid", responseGetAll.Message)
+	})
+	t.Run("Category_GetAll_Sort_Fail", func(t *testing.T) {
+		request := newTestRequest(categoryUrl+"?sort=password", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGetAll web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGetAll)
+
+		require.Equal(t, http.StatusBadRequest, responseGetAll.Code)
+		require.Equal(t, "BAD REQUEST", responseGetAll.Status)
+		require.Equal(t, "cannot sort by password", responseGetAll.Message)
+	})
+}
+
+type testCategoryDeleteJSON struct {
+	Code   int                        `json:"code"`
+	Status string                     `json:"status"`
+	Data   web.CategoryDeleteResponse `json:"data"`
+}
+
+func TestDeleteCategoryStrategies(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+
+	t.Run("Category_Delete_Strategy_Invalid_Fail", func(t *testing.T) {
+		category := database.CategorySeeder(db, testUser.ID, 1)[0]
+		deleteUrl := categoryUrl + "/" + strconv.Itoa(category.ID) + "?strategy=archive"
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+	t.Run("Category_Delete_Cascade_Success", func(t *testing.T) {
+		parent := database.CategorySeeder(db, testUser.ID, 1)
+		child := createTestCategory(fmt.Sprintf(`{"name": "Child", "parent_id": %d}`, parent[0].ID))
+		database.NoteSeeder(db, parent, 2)
+		database.NoteSeeder(db, []domain.Category{{ID: child.ID, UserID: testUser.ID}}, 1)
+		deleteUrl := categoryUrl + "/" + strconv.Itoa(parent[0].ID) + "?strategy=cascade"
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseDelete testCategoryDeleteJSON
+		json.Unmarshal(responseBody, &responseDelete)
+
+		require.Equal(t, http.StatusOK, responseDelete.Code)
+		require.Equal(t, int64(3), responseDelete.Data.Notes)
+		require.Equal(t, int64(1), responseDelete.Data.Subcategories)
+
+		var count int64
+		db.Model(&domain.Note{}).Where("category_id IN ?", []int{parent[0].ID, child.ID}).Count(&count)
+		require.Equal(t, int64(0), count)
+	})
+	t.Run("Category_Delete_Reassign_Success", func(t *testing.T) {
+		categories := database.CategorySeeder(db, testUser.ID, 2)
+		database.NoteSeeder(db, categories[:1], 2)
+		deleteUrl := fmt.Sprintf("%s/%d?strategy=reassign&target=%d", categoryUrl, categories[0].ID, categories[1].ID)
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseDelete testCategoryDeleteJSON
+		json.Unmarshal(responseBody, &responseDelete)
+
+		require.Equal(t, http.StatusOK, responseDelete.Code)
+		require.Equal(t, int64(2), responseDelete.Data.Notes)
+
+		var count int64
+		db.Model(&domain.Note{}).Where("category_id = ?", categories[1].ID).Count(&count)
+		require.Equal(t, int64(2), count)
+	})
+	t.Run("Category_Delete_Reassign_Target_Fail", func(t *testing.T) {
+		category := database.CategorySeeder(db, testUser.ID, 1)[0]
+		deleteUrl := categoryUrl + "/" + strconv.Itoa(category.ID) + "?strategy=reassign"
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
 }
 
 func TestDeleteCategories(t *testing.T) {
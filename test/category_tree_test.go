@@ -0,0 +1,211 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+type testCategoryTreeJSON struct {
+	Code   int                        `json:"code"`
+	Status string                     `json:"status"`
+	Data   []web.CategoryTreeResponse `json:"data"`
+}
+
+func createTestCategory(requestBody string) web.CategoryJSON {
+	request := newTestRequest(categoryUrl, http.MethodPost, requestBody)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+
+	responseBody, _ := io.ReadAll(recorder.Result().Body)
+	var responseCreate testCategoryJSON
+	json.Unmarshal(responseBody, &responseCreate)
+
+	return responseCreate.Data
+}
+
+func TestCategoryTree(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	root := createTestCategory(`{"name": "Root"}`)
+	child := createTestCategory(fmt.Sprintf(`{"name": "Child", "parent_id": %d}`, root.ID))
+	grandchild := createTestCategory(fmt.Sprintf(`{"name": "Grandchild", "parent_id": %d}`, child.ID))
+	database.NoteSeeder(db, []domain.Category{{ID: grandchild.ID, UserID: testUser.ID}}, 2)
+	rootIdUrl := categoryUrl + "/" + strconv.Itoa(root.ID)
+
+	t.Run("Category_Create_Parent_Success", func(t *testing.T) {
+		require.Equal(t, root.ID, *child.ParentID)
+		require.Equal(t, child.ID, *grandchild.ParentID)
+	})
+	t.Run("Category_Create_Parent_NotFound_Fail", func(t *testing.T) {
+		request := newTestRequest(categoryUrl, http.MethodPost, `{"name": "Orphan", "parent_id": 999999}`)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+	t.Run("Category_GetTree_Success", func(t *testing.T) {
+		request := newTestRequest(categoryUrl+"/tree", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testCategoryTreeJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		var rootNode web.CategoryTreeResponse
+		for _, node := range responseGet.Data {
+			if node.ID == root.ID {
+				rootNode = node
+			}
+		}
+		require.Equal(t, 1, len(rootNode.Children))
+		require.Equal(t, child.ID, rootNode.Children[0].ID)
+		require.Equal(t, grandchild.ID, rootNode.Children[0].Children[0].ID)
+	})
+	t.Run("Category_GetDescendants_Success", func(t *testing.T) {
+		request := newTestRequest(rootIdUrl+"/descendants", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testCategoryListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 2, len(responseGet.Data))
+	})
+	t.Run("Note_GetAll_UnderCategory_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?under_category="+strconv.Itoa(root.ID), http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testNoteListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 2, len(responseGet.Data))
+	})
+	t.Run("Category_Update_Cycle_Fail", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"name": "Root", "parent_id": %d}`, grandchild.ID)
+		request := newConditionalRequest(rootIdUrl, http.MethodPut, requestBody, "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+	t.Run("Category_Update_Self_Parent_Fail", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"name": "Root", "parent_id": %d}`, root.ID)
+		request := newConditionalRequest(rootIdUrl, http.MethodPut, requestBody, "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+	t.Run("Category_Delete_HasChildren_Fail", func(t *testing.T) {
+		request := newConditionalRequest(categoryUrl+"/"+strconv.Itoa(child.ID), http.MethodDelete, "", "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusConflict, response.StatusCode)
+	})
+	t.Run("Category_Update_Move_Success", func(t *testing.T) {
+		request := newConditionalRequest(categoryUrl+"/"+strconv.Itoa(child.ID), http.MethodPut,
+			`{"name": "Child", "parent_id": null}`, "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate testCategoryJSON
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusOK, responseUpdate.Code)
+		require.Nil(t, responseUpdate.Data.ParentID)
+	})
+}
+
+func TestCategoryTreeTrash(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	top := createTestCategory(`{"name": "Top"}`)
+	middle := createTestCategory(fmt.Sprintf(`{"name": "Middle", "parent_id": %d}`, top.ID))
+	bottom := createTestCategory(fmt.Sprintf(`{"name": "Bottom", "parent_id": %d}`, middle.ID))
+	notes := database.NoteSeeder(db, []domain.Category{{ID: bottom.ID, UserID: testUser.ID}}, 1)
+	e.ServeHTTP(httptest.NewRecorder(), newConditionalRequest(
+		categoryUrl+"/"+strconv.Itoa(middle.ID)+"?strategy=cascade", http.MethodDelete, "", "*"))
+	topIdUrl := categoryUrl + "/" + strconv.Itoa(top.ID)
+
+	t.Run("Note_Restore_Trashed_Parent_Success", func(t *testing.T) {
+		restoreUrl := noteUrl + "/" + strconv.Itoa(notes[0].ID) + "/restore"
+		e.ServeHTTP(httptest.NewRecorder(), newTestRequest(restoreUrl, http.MethodPost, ""))
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, newTestRequest(categoryUrl+"/"+strconv.Itoa(bottom.ID), http.MethodGet, ""))
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseGet testCategoryJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Nil(t, responseGet.Data.ParentID)
+	})
+	t.Run("Category_Update_Cycle_Trashed_Fail", func(t *testing.T) {
+		// A live category below a trashed one, as restores used to leave them.
+		db.Model(&domain.Category{}).Where("id = ?", bottom.ID).Update("parent_id", middle.ID)
+		requestBody := fmt.Sprintf(`{"name": "Top", "parent_id": %d}`, bottom.ID)
+		request := newConditionalRequest(topIdUrl, http.MethodPut, requestBody, "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+		require.Equal(t, "category can not be moved below one of its descendants", responseUpdate.Message)
+	})
+	t.Run("Category_GetDescendants_Loop_Success", func(t *testing.T) {
+		db.Unscoped().Model(&domain.Category{}).Where("id = ?", middle.ID).Update("deleted_at", nil)
+		db.Model(&domain.Category{}).Where("id = ?", top.ID).Update("parent_id", bottom.ID)
+		request := newTestRequest(topIdUrl+"/descendants", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testCategoryListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 2, len(responseGet.Data))
+	})
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/cli"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func runCLI(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	command := cli.New(strings.NewReader(stdin), &stdout, &stderr)
+	command.Connect = func() (*gorm.DB, error) {
+		return db, nil
+	}
+
+	code := command.Run(args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLI(t *testing.T) {
+	defer deleteAuthTestUsers()
+	email := "auth-" + strings.ToLower(helper.RandomString(10)) + "@example.com"
+
+	t.Run("CLI_Help_Success", func(t *testing.T) {
+		code, stdout, _ := runCLI("", "--help")
+		require.Equal(t, cli.ExitOK, code)
+		require.Contains(t, stdout, "user")
+
+		code, stdout, _ = runCLI("", "seed", "--help")
+		require.Equal(t, cli.ExitOK, code)
+		require.Contains(t, stdout, "-deterministic-seed")
+	})
+	t.Run("CLI_Usage_Fail", func(t *testing.T) {
+		for _, args := range [][]string{{"bogus"}, {"migrate"}, {"migrate", "down", "x"}, {"seed", "--notes", "x"}, {"export"}} {
+			code, _, stderr := runCLI("", args...)
+			require.Equal(t, cli.ExitUsage, code, args)
+			require.Contains(t, stderr, "usage:")
+		}
+	})
+	t.Run("CLI_User_Create_Success", func(t *testing.T) {
+		code, stdout, stderr := runCLI("secret-password\n", "user", "create", "--email", email, "--role", domain.RoleViewer)
+		require.Equal(t, cli.ExitOK, code, stderr)
+
+		var user web.UserResponse
+		require.Nil(t, json.Unmarshal([]byte(stdout), &user))
+		require.Equal(t, email, user.Email)
+		require.Equal(t, domain.RoleViewer, user.Role)
+		require.NotEmpty(t, loginTestUser(email, "secret-password").AccessToken)
+	})
+	t.Run("CLI_User_Create_Fail", func(t *testing.T) {
+		code, _, stderr := runCLI("", "user", "create", "--email", email, "--password", "secret-password")
+		require.Equal(t, cli.ExitError, code)
+		require.Contains(t, stderr, "email already registered")
+
+		code, _, stderr = runCLI("", "user", "create", "--email", "auth-x@example.com", "--password", "secret-password", "--role", "owner")
+		require.Equal(t, cli.ExitError, code)
+		require.Contains(t, stderr, "role must be one of")
+	})
+	t.Run("CLI_Config_Print_Success", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "cli-test-secret")
+		code, stdout, _ := runCLI("", "config", "print")
+		require.Equal(t, cli.ExitOK, code)
+		require.Contains(t, stdout, "JWT_SECRET=********")
+		require.NotContains(t, stdout, "cli-test-secret")
+	})
+	t.Run("CLI_Serve_No_JWT_Secret_Fail", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "")
+		code, _, stderr := runCLI("", "serve")
+		require.Equal(t, cli.ExitError, code)
+		require.Contains(t, stderr, "JWT_SECRET is not set")
+	})
+}
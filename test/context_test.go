@@ -0,0 +1,64 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/naomigrain/echo-crud-notes/service"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestRequestContext(t *testing.T) {
+	t.Run("Context_Deadline_Fail", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		request := newTestRequest(noteUrl, http.MethodGet, "").WithContext(ctx)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+		require.Equal(t, "GATEWAY TIMEOUT", responseGet.Status)
+	})
+	t.Run("Context_Canceled_Fail", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		request := newTestRequest(categoryUrl+"/1", http.MethodGet, "").WithContext(ctx)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, exception.StatusClientClosedRequest, response.StatusCode)
+		require.Equal(t, "CLIENT CLOSED REQUEST", responseGet.Status)
+	})
+	t.Run("Context_Query_Canceled_Fail", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		txManager := service.NewTxManager(db)
+		errTx := txManager.ReadOnly(ctx, func(tx *gorm.DB) error {
+			cancel()
+			_, errFind := repository.NewTagRepository().FindAll(ctx, tx, testUser.ID)
+			return errFind
+		})
+
+		require.ErrorIs(t, errTx, context.Canceled)
+	})
+}
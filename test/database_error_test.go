@@ -0,0 +1,73 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/repository"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestDatabaseError(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	ctx := context.Background()
+
+	t.Run("Tag_Save_Duplicate_Fail", func(t *testing.T) {
+		tagRepository := repository.NewTagRepository()
+		_, errSave := tagRepository.Save(ctx, db, domain.Tag{Name: "duplicate", UserID: testUser.ID})
+		require.Nil(t, errSave)
+
+		_, errSave = tagRepository.Save(ctx, db, domain.Tag{Name: "duplicate", UserID: testUser.ID})
+		var errConflict *exception.ConflictError
+		require.ErrorAs(t, errSave, &errConflict)
+
+		res := exception.NewErrorResponse(errSave)
+		require.Equal(t, http.StatusConflict, res.Code)
+		require.Equal(t, "tag already exists", res.Message)
+	})
+	t.Run("Note_Save_MissingCategory_Fail", func(t *testing.T) {
+		_, errSave := repository.NewNoteRepositoryImpl().Save(ctx, db, domain.Note{
+			Title:      "orphan",
+			Body:       "orphan",
+			CategoryID: 999999,
+			UserID:     testUser.ID,
+		})
+		var errReference *exception.ReferenceError
+		require.ErrorAs(t, errSave, &errReference)
+
+		res := exception.NewErrorResponse(errSave)
+		require.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		require.Equal(t, "note references a record that does not exist", res.Message)
+	})
+	t.Run("Category_FindById_NotFound_Fail", func(t *testing.T) {
+		_, errFind := repository.NewCategoryRepository().FindById(ctx, db, testUser.ID, 999999)
+		require.ErrorIs(t, errFind, gorm.ErrRecordNotFound)
+
+		res := exception.NewErrorResponse(errFind)
+		require.Equal(t, http.StatusNotFound, res.Code)
+		require.Equal(t, "record not found", res.Message)
+	})
+	t.Run("Error_Unknown_Hidden_Success", func(t *testing.T) {
+		res := exception.NewErrorResponse(errors.New(`relation "notes" does not exist`))
+		require.Equal(t, http.StatusInternalServerError, res.Code)
+		require.Equal(t, "internal server error", res.Message)
+	})
+	t.Run("Error_Echo_HTTP_Status_Success", func(t *testing.T) {
+		res := exception.NewErrorResponse(echo.ErrMethodNotAllowed)
+		require.Equal(t, http.StatusMethodNotAllowed, res.Code)
+		require.Equal(t, "METHOD NOT ALLOWED", res.Status)
+		require.Equal(t, "Method Not Allowed", res.Message)
+
+		res = exception.NewErrorResponse(echo.NewHTTPError(http.StatusRequestEntityTooLarge))
+		require.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+		require.Equal(t, "REQUEST ENTITY TOO LARGE", res.Status)
+		require.Equal(t, "Request Entity Too Large", res.Message)
+	})
+}
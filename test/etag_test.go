@@ -0,0 +1,175 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteConcurrency(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 1)
+	note := database.NoteSeeder(db, categoryList, 1)[0]
+	noteIdUrl := noteUrl + "/" + strconv.Itoa(note.ID)
+	requestBody := fmt.Sprintf(`{"title": "Title edited", "body": "Body edited", "id_category": %d}`, categoryList[0].ID)
+
+	t.Run("Note_GetById_ETag_Success", func(t *testing.T) {
+		request := newTestRequest(noteIdUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, `"1"`, response.Header.Get("ETag"))
+	})
+	t.Run("Note_GetById_NotModified_Success", func(t *testing.T) {
+		request := newTestRequest(noteIdUrl, http.MethodGet, "")
+		request.Header.Add("If-None-Match", `W/"1"`)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		require.Equal(t, http.StatusNotModified, response.StatusCode)
+		require.Empty(t, responseBody)
+	})
+	t.Run("Note_Update_Missing_IfMatch_Fail", func(t *testing.T) {
+		request := newTestRequest(noteIdUrl, http.MethodPut, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusPreconditionRequired, responseUpdate.Code)
+		require.Equal(t, "PRECONDITION REQUIRED", responseUpdate.Status)
+	})
+	t.Run("Note_Update_IfMatch_Success", func(t *testing.T) {
+		request := newConditionalRequest(noteIdUrl, http.MethodPut, requestBody, helper.ETag(1))
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate testNoteJSON
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusOK, responseUpdate.Code)
+		require.Equal(t, 2, responseUpdate.Data.Version)
+		require.Equal(t, `"2"`, response.Header.Get("ETag"))
+	})
+	t.Run("Note_Update_Stale_Fail", func(t *testing.T) {
+		request := newConditionalRequest(noteIdUrl, http.MethodPut, requestBody, helper.ETag(1))
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusPreconditionFailed, responseUpdate.Code)
+		require.Equal(t, "PRECONDITION FAILED", responseUpdate.Status)
+		require.Equal(t, "note has been modified since it was read", responseUpdate.Message)
+	})
+	t.Run("Note_Delete_Stale_Fail", func(t *testing.T) {
+		request := newConditionalRequest(noteIdUrl, http.MethodDelete, "", helper.ETag(1))
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+	})
+	t.Run("Note_Delete_IfMatch_Success", func(t *testing.T) {
+		request := newConditionalRequest(noteIdUrl, http.MethodDelete, "", helper.ETag(2))
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+}
+
+func TestCategoryConcurrency(t *testing.T) {
+	defer database.DeleteCategoryRecords(db)
+	category := database.CategorySeeder(db, testUser.ID, 1)[0]
+	categoryIdUrl := categoryUrl + "/" + strconv.Itoa(category.ID)
+
+	t.Run("Category_GetById_ETag_Success", func(t *testing.T) {
+		request := newTestRequest(categoryIdUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, `"1"`, response.Header.Get("ETag"))
+	})
+	t.Run("Category_Update_Stale_Fail", func(t *testing.T) {
+		request := newConditionalRequest(categoryIdUrl, http.MethodPut, `{"name": "Category renamed"}`, helper.ETag(5))
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusPreconditionFailed, responseUpdate.Code)
+		require.Equal(t, "category has been modified since it was read", responseUpdate.Message)
+	})
+	t.Run("Category_Update_IfMatch_List_Success", func(t *testing.T) {
+		request := newConditionalRequest(categoryIdUrl, http.MethodPut, `{"name": "Category renamed"}`,
+			helper.ETag(5)+", "+helper.ETag(1))
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, `"2"`, response.Header.Get("ETag"))
+	})
+	t.Run("Category_Update_IfMatch_Invalid_Fail", func(t *testing.T) {
+		request := newConditionalRequest(categoryIdUrl, http.MethodPut, `{"name": "Category renamed"}`,
+			helper.ETag(2)+", W/"+helper.ETag(2))
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusBadRequest, responseUpdate.Code)
+		require.Equal(t, "If-Match header is invalid", responseUpdate.Message)
+	})
+	t.Run("Category_Delete_Missing_IfMatch_Fail", func(t *testing.T) {
+		request := newTestRequest(categoryIdUrl, http.MethodDelete, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusPreconditionRequired, response.StatusCode)
+	})
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/stretchr/testify/require"
+)
+
+// testMigrations are numbered after the real ones, so they stack on top of the
+// schema the other tests use and are reverted again at the end.
+var testMigrations = fstest.MapFS{
+	"9001_create_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id bigserial PRIMARY KEY)")},
+	"9001_create_widgets.down.sql": {Data: []byte("DROP TABLE widgets")},
+	"9002_add_widget_name.up.sql": {Data: []byte(`ALTER TABLE widgets ADD COLUMN name varchar(50);
+		INSERT INTO widgets (name) VALUES ('first')`)},
+	"9002_add_widget_name.down.sql": {Data: []byte("ALTER TABLE widgets DROP COLUMN name")},
+}
+
+func TestMigrations(t *testing.T) {
+	migrator, errLoad := database.NewMigrator(db, testMigrations)
+	require.Nil(t, errLoad)
+	defer migrator.Down(2)
+
+	t.Run("Migration_Status_Pending_Success", func(t *testing.T) {
+		statuses, errStatus := migrator.Status()
+		require.Nil(t, errStatus)
+
+		pending := 0
+		for _, status := range statuses {
+			if status.AppliedAt == nil {
+				pending++
+			}
+		}
+		require.Equal(t, 2, pending)
+	})
+	t.Run("Migration_Up_Concurrent_Success", func(t *testing.T) {
+		var wg sync.WaitGroup
+		applied := make([]int, 2)
+		errs := make([]error, 2)
+		for i := range applied {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				migrations, errUp := migrator.Up()
+				applied[i], errs[i] = len(migrations), errUp
+			}(i)
+		}
+		wg.Wait()
+
+		require.Nil(t, errs[0])
+		require.Nil(t, errs[1])
+		require.Equal(t, 2, applied[0]+applied[1])
+
+		var count int64
+		db.Table("widgets").Count(&count)
+		require.Equal(t, int64(1), count)
+
+		pending, errPending := migrator.Pending()
+		require.Nil(t, errPending)
+		require.Equal(t, 0, pending)
+	})
+	t.Run("Migration_Down_Success", func(t *testing.T) {
+		reverted, errDown := migrator.Down(1)
+		require.Nil(t, errDown)
+		require.Equal(t, 1, len(reverted))
+		require.Equal(t, 9002, reverted[0].Version)
+		require.False(t, db.Migrator().HasColumn("widgets", "name"))
+
+		reverted, errDown = migrator.Down(1)
+		require.Nil(t, errDown)
+		require.Equal(t, 9001, reverted[0].Version)
+		require.False(t, db.Migrator().HasTable("widgets"))
+	})
+	t.Run("Migration_Down_Missing_Fail", func(t *testing.T) {
+		_, errDown := migrator.Down(1)
+		require.ErrorIs(t, errDown, database.ErrMigrationMissing)
+
+		var count int64
+		db.Table("users").Count(&count)
+		require.NotEqual(t, int64(0), count)
+	})
+}
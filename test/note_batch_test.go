@@ -0,0 +1,142 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+type testNoteBatchJSON struct {
+	Code   int                   `json:"code"`
+	Status string                `json:"status"`
+	Data   web.NoteBatchResponse `json:"data"`
+}
+
+func sendNoteBatch(requestBody string) testNoteBatchJSON {
+	request := newTestRequest(noteUrl+"/batch", http.MethodPost, requestBody)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+
+	responseBody, _ := io.ReadAll(recorder.Result().Body)
+	var responseBatch testNoteBatchJSON
+	json.Unmarshal(responseBody, &responseBatch)
+
+	return responseBatch
+}
+
+func TestNoteBatch(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 1)
+	idCategory := categoryList[0].ID
+	notes := database.NoteSeeder(db, categoryList, 2)
+
+	t.Run("Note_Batch_Atomic_Success", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"operations": [
+			{"op": "create", "note": {"title": "First", "body": "Body", "id_category": %d, "tags": ["batch"]}},
+			{"op": "create", "note": {"title": "Second", "body": "Body", "id_category": %d}},
+			{"op": "update", "id": %d, "version": 1, "note": {"title": "Updated", "body": "Body", "id_category": %d}},
+			{"op": "delete", "id": %d, "version": 1}
+		]}`, idCategory, idCategory, notes[0].ID, idCategory, notes[1].ID)
+		responseBatch := sendNoteBatch(requestBody)
+
+		require.Equal(t, http.StatusOK, responseBatch.Code)
+		require.True(t, responseBatch.Data.Committed)
+		require.Equal(t, 4, responseBatch.Data.Succeeded)
+		require.Equal(t, []string{"batch"}, responseBatch.Data.Results[0].Note.Tags)
+		require.Equal(t, "Updated", responseBatch.Data.Results[2].Note.Title)
+		require.Equal(t, 2, responseBatch.Data.Results[2].Note.Version)
+
+		var count int64
+		db.Model(&domain.Note{}).Where("user_id = ?", testUser.ID).Count(&count)
+		require.Equal(t, int64(3), count)
+	})
+	t.Run("Note_Batch_Atomic_Fail", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"mode": "atomic", "operations": [
+			{"op": "create", "note": {"title": "Third", "body": "Body", "id_category": %d}},
+			{"op": "update", "id": %d, "version": 1, "note": {"title": "Stale", "body": "Body", "id_category": %d}}
+		]}`, idCategory, notes[0].ID, idCategory)
+		responseBatch := sendNoteBatch(requestBody)
+
+		require.Equal(t, http.StatusUnprocessableEntity, responseBatch.Code)
+		require.False(t, responseBatch.Data.Committed)
+		require.Equal(t, web.NoteBatchRolledBack, responseBatch.Data.Results[0].Status)
+		require.Equal(t, web.NoteBatchFailed, responseBatch.Data.Results[1].Status)
+		require.Equal(t, http.StatusPreconditionFailed, responseBatch.Data.Results[1].Error.Code)
+
+		var count int64
+		db.Model(&domain.Note{}).Where("title = ?", "Third").Count(&count)
+		require.Equal(t, int64(0), count)
+	})
+	t.Run("Note_Batch_BestEffort_Success", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"mode": "best_effort", "operations": [
+			{"op": "create", "note": {"title": "Fourth", "body": "Body", "id_category": %d}},
+			{"op": "create", "note": {"title": "X", "body": "Body", "id_category": %d}},
+			{"op": "delete", "id": %d, "version": 1}
+		]}`, idCategory, idCategory, notes[1].ID)
+		responseBatch := sendNoteBatch(requestBody)
+
+		require.Equal(t, http.StatusOK, responseBatch.Code)
+		require.True(t, responseBatch.Data.Committed)
+		require.Equal(t, 1, responseBatch.Data.Succeeded)
+		require.Equal(t, 2, responseBatch.Data.Failed)
+		require.Equal(t, web.NoteBatchOK, responseBatch.Data.Results[0].Status)
+		require.Equal(t, http.StatusUnprocessableEntity, responseBatch.Data.Results[1].Error.Code)
+		require.Equal(t, http.StatusNotFound, responseBatch.Data.Results[2].Error.Code)
+	})
+	t.Run("Note_Batch_BestEffort_Insert_Fail_Success", func(t *testing.T) {
+		// Postgres refuses NUL bytes in text, which fails the batched insert
+		// after every check passed.
+		requestBody := fmt.Sprintf(`{"mode": "best_effort", "operations": [
+			{"op": "create", "note": {"title": "Fifth", "body": "Body", "id_category": %d}},
+			{"op": "create", "note": {"title": "Broken", "body": "Bo\u0000dy", "id_category": %d}},
+			{"op": "create", "note": {"title": "Sixth", "body": "Body", "id_category": %d}}
+		]}`, idCategory, idCategory, idCategory)
+		responseBatch := sendNoteBatch(requestBody)
+
+		require.Equal(t, http.StatusOK, responseBatch.Code)
+		require.Equal(t, 2, responseBatch.Data.Succeeded)
+		require.Equal(t, 1, responseBatch.Data.Failed)
+		require.Equal(t, web.NoteBatchOK, responseBatch.Data.Results[0].Status)
+		require.Equal(t, web.NoteBatchFailed, responseBatch.Data.Results[1].Status)
+		require.Equal(t, web.NoteBatchOK, responseBatch.Data.Results[2].Status)
+
+		var count int64
+		db.Model(&domain.Note{}).Where("title IN ?", []string{"Fifth", "Sixth"}).Count(&count)
+		require.Equal(t, int64(2), count)
+	})
+	t.Run("Note_Batch_Atomic_Insert_Fail", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"operations": [
+			{"op": "create", "note": {"title": "Seventh", "body": "Body", "id_category": %d}},
+			{"op": "create", "note": {"title": "Broken", "body": "Bo\u0000dy", "id_category": %d}},
+			{"op": "create", "note": {"title": "Eighth", "body": "Body", "id_category": %d}}
+		]}`, idCategory, idCategory, idCategory)
+		responseBatch := sendNoteBatch(requestBody)
+
+		require.Equal(t, http.StatusUnprocessableEntity, responseBatch.Code)
+		require.False(t, responseBatch.Data.Committed)
+		require.Equal(t, 1, responseBatch.Data.Failed)
+		require.Equal(t, web.NoteBatchRolledBack, responseBatch.Data.Results[0].Status)
+		require.Equal(t, web.NoteBatchFailed, responseBatch.Data.Results[1].Status)
+		require.NotNil(t, responseBatch.Data.Results[1].Error)
+		require.Equal(t, web.NoteBatchRolledBack, responseBatch.Data.Results[2].Status)
+		require.Nil(t, responseBatch.Data.Results[2].Error)
+
+		var count int64
+		db.Model(&domain.Note{}).Where("title IN ?", []string{"Seventh", "Eighth"}).Count(&count)
+		require.Equal(t, int64(0), count)
+	})
+	t.Run("Note_Batch_Mode_Invalid_Fail", func(t *testing.T) {
+		responseBatch := sendNoteBatch(`{"mode": "some", "operations": [{"op": "delete", "id": 1, "version": 1}]}`)
+
+		require.Equal(t, http.StatusBadRequest, responseBatch.Code)
+	})
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteMarkdown(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 1)
+	idCategory := categoryList[0].ID
+
+	markdownNote := createTestNote(fmt.Sprintf(`{"title": "Markdown", "format": "markdown", "id_category": %d,
+		"body": "# Plan\n\n**bold** <script>alert(1)</script> [link](javascript:alert(1))\n\n`+"```go\\nfmt.Println()\\n```"+`"}`,
+		idCategory))
+	plainNote := createTestNote(fmt.Sprintf(`{"title": "Plain", "body": "a <b>tag</b>", "id_category": %d}`, idCategory))
+
+	t.Run("Note_Create_Format_Success", func(t *testing.T) {
+		require.Equal(t, web.NoteFormatMarkdown, markdownNote.Format)
+		require.Equal(t, web.NoteFormatPlain, plainNote.Format)
+	})
+	t.Run("Note_Create_Format_Fail", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"title": "Rich", "body": "Body", "format": "rtf", "id_category": %d}`, idCategory)
+		request := newTestRequest(noteUrl, http.MethodPost, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseCreate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseCreate)
+
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "format must be one of [plain markdown]", responseCreate.Message)
+	})
+	t.Run("Note_Render_Markdown_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"/"+strconv.Itoa(markdownNote.ID)+"?render=html", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseGet testNoteJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Contains(t, responseGet.Data.HTML, "<h1>Plan</h1>")
+		require.Contains(t, responseGet.Data.HTML, "<strong>bold</strong>")
+		require.Contains(t, responseGet.Data.HTML, `<code class="language-go">`)
+		require.NotContains(t, responseGet.Data.HTML, "<script")
+		require.NotContains(t, responseGet.Data.HTML, "javascript:")
+	})
+	t.Run("Note_Render_Plain_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"/"+strconv.Itoa(plainNote.ID)+"?render=html", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseGet testNoteJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, "<p>a &lt;b&gt;tag&lt;/b&gt;</p>\n", responseGet.Data.HTML)
+	})
+	t.Run("Note_Render_Fail", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"/"+strconv.Itoa(plainNote.ID)+"?render=pdf", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseGet web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusBadRequest, responseGet.Code)
+		require.Equal(t, "render is invalid", responseGet.Message)
+	})
+	t.Run("Note_List_Excerpt_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?sort=id", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseList testNoteListJSON
+		json.Unmarshal(responseBody, &responseList)
+
+		require.Equal(t, http.StatusOK, responseList.Code)
+		require.Equal(t, "Plan bold alert(1) link fmt.Println()", responseList.Data[0].Excerpt)
+		require.Equal(t, "a <b>tag</b>", responseList.Data[1].Excerpt)
+	})
+}
@@ -0,0 +1,193 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+type testNoteRevisionJSON struct {
+	Code   int                      `json:"code"`
+	Status string                   `json:"status"`
+	Data   web.NoteRevisionResponse `json:"data"`
+}
+
+type testNoteRevisionListJSON struct {
+	Code   int                        `json:"code"`
+	Status string                     `json:"status"`
+	Data   []web.NoteRevisionResponse `json:"data"`
+}
+
+type testNoteRevisionDiffJSON struct {
+	Code   int                          `json:"code"`
+	Status string                       `json:"status"`
+	Data   web.NoteRevisionDiffResponse `json:"data"`
+}
+
+func TestNoteRevisions(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 1)
+	categoryID := categoryList[0].ID
+
+	requestBody := fmt.Sprintf(`{"title": "Groceries", "body": "milk\neggs", "id_category": %d}`, categoryID)
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, newTestRequest(noteUrl, http.MethodPost, requestBody))
+	responseBody, _ := io.ReadAll(recorder.Result().Body)
+	var created testNoteJSON
+	json.Unmarshal(responseBody, &created)
+	revisionUrl := noteUrl + "/" + strconv.Itoa(created.Data.ID) + "/revisions"
+
+	requestBody = fmt.Sprintf(`{"title": "Groceries", "body": "milk\nbread", "id_category": %d}`, categoryID)
+	e.ServeHTTP(httptest.NewRecorder(),
+		newConditionalRequest(noteUrl+"/"+strconv.Itoa(created.Data.ID), http.MethodPut, requestBody, "*"))
+
+	t.Run("Revision_GetAll_Success", func(t *testing.T) {
+		request := newTestRequest(revisionUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testNoteRevisionListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 2, len(responseGet.Data))
+		require.Equal(t, 2, responseGet.Data[0].Revision)
+		require.Equal(t, "milk\nbread", responseGet.Data[0].Body)
+		require.Equal(t, testUser.ID, responseGet.Data[0].AuthorID)
+	})
+	t.Run("Revision_GetByRevision_Success", func(t *testing.T) {
+		request := newTestRequest(revisionUrl+"/1", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testNoteRevisionJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, "milk\neggs", responseGet.Data.Body)
+	})
+	t.Run("Revision_GetByRevision_NotFound_Fail", func(t *testing.T) {
+		request := newTestRequest(revisionUrl+"/99", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusNotFound, responseGet.Code)
+		require.Equal(t, "revision not found", responseGet.Message)
+	})
+	t.Run("Revision_Diff_Success", func(t *testing.T) {
+		request := newTestRequest(revisionUrl+"/diff?from=1&to=2", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseDiff testNoteRevisionDiffJSON
+		json.Unmarshal(responseBody, &responseDiff)
+
+		require.Equal(t, http.StatusOK, responseDiff.Code)
+		require.Equal(t, "--- revision 1\n+++ revision 2\n@@ -1,4 +1,4 @@\n Groceries\n \n milk\n-eggs\n+bread\n",
+			responseDiff.Data.Diff)
+	})
+	t.Run("Revision_Diff_Large_Success", func(t *testing.T) {
+		lines := make([]string, helper.MaxDiffLines-1)
+		for i := range lines {
+			lines[i] = "line " + strconv.Itoa(i)
+		}
+		from := strings.Join(lines, "\n")
+		lines[2500] = "changed"
+		to := strings.Join(lines, "\n")
+
+		diff, errDiff := helper.UnifiedDiff("a", "b", from, to)
+
+		require.Nil(t, errDiff)
+		require.Equal(t, "--- a\n+++ b\n@@ -2498,7 +2498,7 @@\n line 2497\n line 2498\n line 2499\n-line 2500\n+changed\n line 2501\n line 2502\n line 2503\n",
+			diff)
+	})
+	t.Run("Revision_Diff_Validation_Fail", func(t *testing.T) {
+		request := newTestRequest(revisionUrl+"/diff?from=1", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+	t.Run("Revision_Restore_Success", func(t *testing.T) {
+		request := newTestRequest(revisionUrl+"/1/restore", http.MethodPost, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRestore testNoteJSON
+		json.Unmarshal(responseBody, &responseRestore)
+
+		require.Equal(t, http.StatusOK, responseRestore.Code)
+		require.Equal(t, "milk\neggs", responseRestore.Data.Body)
+
+		recorder = httptest.NewRecorder()
+		e.ServeHTTP(recorder, newTestRequest(revisionUrl+"/3", http.MethodGet, ""))
+		require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	})
+	t.Run("Revision_Diff_TooLarge_Fail", func(t *testing.T) {
+		body, _ := json.Marshal(strings.Repeat("x\n", 300000))
+		requestBody := fmt.Sprintf(`{"title": "Groceries", "body": %s, "id_category": %d}`, body, categoryID)
+		e.ServeHTTP(httptest.NewRecorder(),
+			newConditionalRequest(noteUrl+"/"+strconv.Itoa(created.Data.ID), http.MethodPut, requestBody, "*"))
+
+		request := newTestRequest(revisionUrl+"/diff?from=1&to=4", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseDiff web.ErrorResponse
+		json.Unmarshal(responseBody, &responseDiff)
+
+		require.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+		require.Equal(t, "revisions with more than 5000 lines can not be compared", responseDiff.Message)
+	})
+	t.Run("Revision_Seeded_Note_Update_Success", func(t *testing.T) {
+		seeded := database.NoteSeeder(db, categoryList, 1)[0]
+		requestBody := fmt.Sprintf(`{"title": "Updated", "body": "Updated body", "id_category": %d}`, categoryID)
+		e.ServeHTTP(httptest.NewRecorder(),
+			newConditionalRequest(noteUrl+"/"+strconv.Itoa(seeded.ID), http.MethodPut, requestBody, "*"))
+
+		request := newTestRequest(noteUrl+"/"+strconv.Itoa(seeded.ID)+"/revisions", http.MethodGet, "")
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseGet testNoteRevisionListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, 2, len(responseGet.Data))
+		require.Equal(t, seeded.Title, responseGet.Data[1].Title)
+		require.Equal(t, "Updated", responseGet.Data[0].Title)
+	})
+}
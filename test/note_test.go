@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,12 +9,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/config"
 	"github.com/naomigrain/echo-crud-notes/helper"
 	"github.com/naomigrain/echo-crud-notes/model/domain"
 	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/repository"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,6 +32,8 @@ type testNoteListJSON struct {
 	Code   int                `json:"code"`
 	Status string             `json:"status"`
 	Data   []web.NoteResponse `json:"data"`
+	Meta   web.PageMeta       `json:"meta"`
+	Links  web.PageLinks      `json:"links"`
 }
 
 var noteUrl string = "http://127.0.0.1:8000/api/notes"
@@ -43,7 +50,7 @@ func findCategoryInList(id int, categoryList []domain.Category) domain.Category
 }
 
 func TestGetNotes(t *testing.T) {
-	categoryList = database.CategorySeeder(db, 3)
+	categoryList = database.CategorySeeder(db, testUser.ID, 3)
 	noteList := database.NoteSeeder(db, categoryList, 5)
 	defer database.DeleteAllRecords(db)
 
@@ -110,11 +117,77 @@ func TestGetNotes(t *testing.T) {
 			require.Equal(t, categoryNote.Name, nD.Category)
 		}
 	})
+
+	t.Run("Note_Get_All_Pagination_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?page=2&pageSize=2", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Body
+
+		responseBody, _ := io.ReadAll(response)
+		var noteResponse testNoteListJSON
+		json.Unmarshal(responseBody, &noteResponse)
+
+		require.Equal(t, http.StatusOK, noteResponse.Code)
+		require.Equal(t, 2, len(noteResponse.Data))
+		require.Equal(t, noteList[2].ID, noteResponse.Data[0].ID)
+		require.Equal(t, 2, noteResponse.Meta.Page)
+		require.Equal(t, 2, noteResponse.Meta.PageSize)
+		require.Equal(t, int64(len(noteList)), noteResponse.Meta.TotalItems)
+		require.Equal(t, 3, noteResponse.Meta.TotalPages)
+		require.Contains(t, noteResponse.Links.Next, "page=3")
+		require.Contains(t, noteResponse.Links.Prev, "page=1")
+		require.Contains(t, noteResponse.Links.Last, "page=3")
+	})
+
+	t.Run("Note_Get_All_Filter_Category_Success", func(t *testing.T) {
+		categoryId := noteList[0].CategoryID
+		filterUrl := noteUrl + "?category_id=" + strconv.Itoa(categoryId) + "&sort=-created_at"
+		request := newTestRequest(filterUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Body
+
+		responseBody, _ := io.ReadAll(response)
+		var noteResponse testNoteListJSON
+		json.Unmarshal(responseBody, &noteResponse)
+
+		expected := 0
+		for _, n := range noteList {
+			if n.CategoryID == categoryId {
+				expected++
+			}
+		}
+		category := findCategoryInList(categoryId, categoryList)
+		require.Equal(t, http.StatusOK, noteResponse.Code)
+		require.Equal(t, expected, len(noteResponse.Data))
+		for _, nD := range noteResponse.Data {
+			require.Equal(t, category.Name, nD.Category)
+		}
+	})
+
+	t.Run("Note_Get_All_Filter_BadRequest_Fail", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?created_after=yesterday", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Body
+
+		responseBody, _ := io.ReadAll(response)
+		var noteResponse web.ErrorResponse
+		json.Unmarshal(responseBody, &noteResponse)
+
+		require.Equal(t, http.StatusBadRequest, noteResponse.Code)
+		require.Equal(t, "BAD REQUEST", noteResponse.Status)
+		require.Equal(t, "created_after is invalid", noteResponse.Message)
+	})
 }
 
 func TestCreateNote(t *testing.T) {
 	createUrl := noteUrl
-	categoryList = database.CategorySeeder(db, 3)
+	categoryList = database.CategorySeeder(db, testUser.ID, 3)
 	defer database.DeleteAllRecords(db)
 
 	t.Run("Note_Create_Success", func(t *testing.T) {
@@ -164,9 +237,9 @@ func TestCreateNote(t *testing.T) {
 		var responseCreate web.ErrorResponse
 		json.Unmarshal(responseBody, &responseCreate)
 
-		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
-		require.Equal(t, "BAD REQUEST", responseCreate.Status)
-		require.Equal(t, "Title is should more than 2 characters", responseCreate.Message)
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "UNPROCESSABLE ENTITY", responseCreate.Status)
+		require.Equal(t, "title must be at least 2 characters in length", responseCreate.Message)
 	})
 
 	t.Run("Note_Create_BadRequest2_Fail", func(t *testing.T) {
@@ -189,9 +262,9 @@ func TestCreateNote(t *testing.T) {
 		var responseCreate web.ErrorResponse
 		json.Unmarshal(responseBody, &responseCreate)
 
-		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
-		require.Equal(t, "BAD REQUEST", responseCreate.Status)
-		require.Equal(t, "Body is should more than 2 characters", responseCreate.Message)
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "UNPROCESSABLE ENTITY", responseCreate.Status)
+		require.Equal(t, "body must be at least 2 characters in length", responseCreate.Message)
 	})
 
 	t.Run("Note_Create_BadRequest3_Fail", func(t *testing.T) {
@@ -212,15 +285,36 @@ func TestCreateNote(t *testing.T) {
 		var responseCreate web.ErrorResponse
 		json.Unmarshal(responseBody, &responseCreate)
 
-		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
-		require.Equal(t, "BAD REQUEST", responseCreate.Status)
-		require.Equal(t, "category did not exists", responseCreate.Message)
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "id_category must be an existing category", responseCreate.Message)
+		require.Equal(t, "category_exists", responseCreate.Errors[0].Rule)
+	})
+	t.Run("Note_Create_Translated_Fail", func(t *testing.T) {
+		requestBody := `{"title": "a", "body": "b", "id_category": 9999999}`
+		request := newTestRequest(createUrl, http.MethodPost, requestBody)
+		request.Header.Add("Accept-Language", "id-ID,id;q=0.9,en;q=0.8")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseCreate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseCreate)
+
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, []web.FieldError{
+			{Field: "title", Rule: "min", Param: "2", Message: "panjang minimal title adalah 2 karakter"},
+			{Field: "body", Rule: "min", Param: "2", Message: "panjang minimal body adalah 2 karakter"},
+			{Field: "id_category", Rule: "category_exists", Param: "", Message: "id_category harus berupa kategori yang sudah ada"},
+		}, responseCreate.Errors)
+		require.Equal(t, responseCreate.Errors[0].Message, responseCreate.Message)
 	})
 }
 
 func TestUpdateNote(t *testing.T) {
 	defer database.DeleteAllRecords(db)
-	categoryList = database.CategorySeeder(db, 3)
+	categoryList = database.CategorySeeder(db, testUser.ID, 3)
 	noteList := database.NoteSeeder(db, categoryList, 5)
 
 	t.Run("Note_Update_Success", func(t *testing.T) {
@@ -236,7 +330,7 @@ func TestUpdateNote(t *testing.T) {
 		requestBody := fmt.Sprintf(
 			`{"title": "%s", "body": "%s", "id_category": %d}`,
 			noteTitle, noteBody, noteCategory.ID)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, helper.ETag(note.Version))
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -266,7 +360,7 @@ func TestUpdateNote(t *testing.T) {
 		requestBody := fmt.Sprintf(
 			`{"title": "%s", "body": "%s", "id_category": %d}`,
 			noteTitle, noteBody, noteCategory.ID)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -294,7 +388,7 @@ func TestUpdateNote(t *testing.T) {
 		requestBody := fmt.Sprintf(
 			`{"title": "%s", "body": "%s", "id_category": %d}`,
 			noteTitle, noteBody, noteCategory.ID)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -304,9 +398,9 @@ func TestUpdateNote(t *testing.T) {
 		var responseCreate web.ErrorResponse
 		json.Unmarshal(responseBody, &responseCreate)
 
-		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
-		require.Equal(t, "BAD REQUEST", responseCreate.Status)
-		require.Equal(t, "Title is should more than 2 characters", responseCreate.Message)
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "UNPROCESSABLE ENTITY", responseCreate.Status)
+		require.Equal(t, "title must be at least 2 characters in length", responseCreate.Message)
 	})
 
 	t.Run("Note_Update_BadRequest2_Fail", func(t *testing.T) {
@@ -322,7 +416,7 @@ func TestUpdateNote(t *testing.T) {
 		requestBody := fmt.Sprintf(
 			`{"title": "%s", "body": "%s", "id_category": %d}`,
 			noteTitle, noteBody, noteCategory.ID)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -332,9 +426,9 @@ func TestUpdateNote(t *testing.T) {
 		var responseCreate web.ErrorResponse
 		json.Unmarshal(responseBody, &responseCreate)
 
-		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
-		require.Equal(t, "BAD REQUEST", responseCreate.Status)
-		require.Equal(t, "Body is should more than 2 characters", responseCreate.Message)
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "UNPROCESSABLE ENTITY", responseCreate.Status)
+		require.Equal(t, "body must be at least 2 characters in length", responseCreate.Message)
 	})
 
 	t.Run("Note_Update_BadRequest3_Fail", func(t *testing.T) {
@@ -349,7 +443,7 @@ func TestUpdateNote(t *testing.T) {
 		requestBody := fmt.Sprintf(
 			`{"title": "%s", "body": "%s", "id_category": %d}`,
 			noteTitle, noteBody, noteCategory)
-		request := newTestRequest(updateUrl, http.MethodPut, requestBody)
+		request := newConditionalRequest(updateUrl, http.MethodPut, requestBody, "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -359,22 +453,22 @@ func TestUpdateNote(t *testing.T) {
 		var responseCreate web.ErrorResponse
 		json.Unmarshal(responseBody, &responseCreate)
 
-		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
-		require.Equal(t, "BAD REQUEST", responseCreate.Status)
-		require.Equal(t, "category does not exists", responseCreate.Message)
+		require.Equal(t, http.StatusUnprocessableEntity, responseCreate.Code)
+		require.Equal(t, "id_category must be an existing category", responseCreate.Message)
+		require.Equal(t, "category_exists", responseCreate.Errors[0].Rule)
 	})
 }
 
 func TestDeleteNote(t *testing.T) {
 	defer database.DeleteAllRecords(db)
-	categoryList = database.CategorySeeder(db, 3)
+	categoryList = database.CategorySeeder(db, testUser.ID, 3)
 	noteList := database.NoteSeeder(db, categoryList, 5)
 
 	t.Run("Note_Delete_Success", func(t *testing.T) {
 		note := noteList[rand.Intn(len(noteList))]
 		deleteUrl := noteUrl + "/" + strconv.Itoa(note.ID)
 
-		request := newTestRequest(deleteUrl, http.MethodDelete, "")
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -392,7 +486,7 @@ func TestDeleteNote(t *testing.T) {
 	t.Run("Note_Delete_NotFound_Fail", func(t *testing.T) {
 		deleteUrl := noteUrl + "/" + strconv.Itoa(9999999)
 
-		request := newTestRequest(deleteUrl, http.MethodDelete, "")
+		request := newConditionalRequest(deleteUrl, http.MethodDelete, "", "*")
 
 		recorder := httptest.NewRecorder()
 		e.ServeHTTP(recorder, request)
@@ -407,3 +501,261 @@ func TestDeleteNote(t *testing.T) {
 		require.Equal(t, "note not found", responseCreate.Message)
 	})
 }
+
+func TestSearchNotes(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 1)
+	bodies := []string{
+		"Buy fresh avocado and bread",
+		"Meeting notes about the quarterly budget",
+		"Avocado toast recipe with lemon",
+	}
+	for i, body := range bodies {
+		requestBody := fmt.Sprintf(
+			`{"title": "Note %d", "body": "%s", "id_category": %d}`,
+			i, body, categoryList[0].ID)
+		request := newTestRequest(noteUrl, http.MethodPost, requestBody)
+		e.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	t.Run("Note_Search_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?q=avocado", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Body
+
+		responseBody, _ := io.ReadAll(response)
+		var noteResponse testNoteListJSON
+		json.Unmarshal(responseBody, &noteResponse)
+
+		require.Equal(t, http.StatusOK, noteResponse.Code)
+		require.Equal(t, "OK", noteResponse.Status)
+		require.Equal(t, 2, len(noteResponse.Data))
+		for _, nD := range noteResponse.Data {
+			require.Contains(t, nD.Snippet, "<mark>")
+		}
+	})
+
+	t.Run("Note_Search_Empty_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?q=spaceship", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Body
+
+		responseBody, _ := io.ReadAll(response)
+		var noteResponse testNoteListJSON
+		json.Unmarshal(responseBody, &noteResponse)
+
+		require.Equal(t, http.StatusOK, noteResponse.Code)
+		require.Equal(t, 0, len(noteResponse.Data))
+	})
+
+	t.Run("Note_Search_Snippet_Escaped_Success", func(t *testing.T) {
+		requestBody := fmt.Sprintf(
+			`{"title": "Launch", "body": "Rocket <script>alert(1)</script> launch", "id_category": %d}`,
+			categoryList[0].ID)
+		e.ServeHTTP(httptest.NewRecorder(), newTestRequest(noteUrl, http.MethodPost, requestBody))
+		request := newTestRequest(noteUrl+"?q=rocket", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Body
+
+		responseBody, _ := io.ReadAll(response)
+		var noteResponse testNoteListJSON
+		json.Unmarshal(responseBody, &noteResponse)
+
+		require.Equal(t, http.StatusOK, noteResponse.Code)
+		require.Equal(t, 1, len(noteResponse.Data))
+		require.Contains(t, noteResponse.Data[0].Snippet, "<mark>Rocket</mark>")
+		require.Contains(t, noteResponse.Data[0].Snippet, "&lt;script&gt;")
+		require.NotContains(t, noteResponse.Data[0].Snippet, "<script>")
+	})
+	t.Run("Note_Search_Snippet_Title_Success", func(t *testing.T) {
+		requestBody := fmt.Sprintf(
+			`{"title": "Telescope", "body": "Nothing else to see here", "id_category": %d}`,
+			categoryList[0].ID)
+		e.ServeHTTP(httptest.NewRecorder(), newTestRequest(noteUrl, http.MethodPost, requestBody))
+		request := newTestRequest(noteUrl+"?q=telescope", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Body
+
+		responseBody, _ := io.ReadAll(response)
+		var noteResponse testNoteListJSON
+		json.Unmarshal(responseBody, &noteResponse)
+
+		require.Equal(t, http.StatusOK, noteResponse.Code)
+		require.Equal(t, 1, len(noteResponse.Data))
+		require.Contains(t, noteResponse.Data[0].Snippet, "<mark>Telescope</mark>")
+	})
+}
+
+func TestTrashNote(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 1)
+	noteList := database.NoteSeeder(db, categoryList, 3)
+
+	deleteNote := func(id int) {
+		request := newConditionalRequest(noteUrl+"/"+strconv.Itoa(id), http.MethodDelete, "", "*")
+		e.ServeHTTP(httptest.NewRecorder(), request)
+	}
+	deleteNote(noteList[0].ID)
+	deleteNote(noteList[1].ID)
+
+	t.Run("Note_Trash_List_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"/trash", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testNoteListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 2, len(responseGet.Data))
+		require.Equal(t, int64(2), responseGet.Meta.TotalItems)
+		for _, nD := range responseGet.Data {
+			require.NotNil(t, nD.DeletedAt)
+		}
+	})
+	t.Run("Note_Trash_Hidden_From_List_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testNoteListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, 1, len(responseGet.Data))
+		require.Equal(t, noteList[2].ID, responseGet.Data[0].ID)
+	})
+	t.Run("Note_Restore_Success", func(t *testing.T) {
+		restoreUrl := noteUrl + "/" + strconv.Itoa(noteList[0].ID) + "/restore"
+		request := newTestRequest(restoreUrl, http.MethodPost, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRestore testNoteJSON
+		json.Unmarshal(responseBody, &responseRestore)
+
+		require.Equal(t, http.StatusOK, responseRestore.Code)
+		require.Equal(t, noteList[0].ID, responseRestore.Data.ID)
+		require.Nil(t, responseRestore.Data.DeletedAt)
+	})
+	t.Run("Note_Restore_NotInTrash_Fail", func(t *testing.T) {
+		restoreUrl := noteUrl + "/" + strconv.Itoa(noteList[2].ID) + "/restore"
+		request := newTestRequest(restoreUrl, http.MethodPost, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseRestore web.ErrorResponse
+		json.Unmarshal(responseBody, &responseRestore)
+
+		require.Equal(t, http.StatusNotFound, responseRestore.Code)
+		require.Equal(t, "note not found", responseRestore.Message)
+	})
+	t.Run("Note_Purge_NotInTrash_Fail", func(t *testing.T) {
+		purgeUrl := noteUrl + "/" + strconv.Itoa(noteList[2].ID) + "/purge"
+		request := newTestRequest(purgeUrl, http.MethodDelete, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+	t.Run("Note_Purge_Success", func(t *testing.T) {
+		purgeUrl := noteUrl + "/" + strconv.Itoa(noteList[1].ID) + "/purge"
+		request := newTestRequest(purgeUrl, http.MethodDelete, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		var count int64
+		db.Unscoped().Model(&domain.Note{}).Where("id = ?", noteList[1].ID).Count(&count)
+		require.Equal(t, int64(0), count)
+	})
+	t.Run("Note_Purge_Expired_Success", func(t *testing.T) {
+		deleteNote(noteList[2].ID)
+		database.PurgeTrash(context.Background(), db, time.Now().Add(-time.Hour))
+
+		var count int64
+		db.Unscoped().Model(&domain.Note{}).Where("id = ?", noteList[2].ID).Count(&count)
+		require.Equal(t, int64(1), count)
+
+		database.PurgeTrash(context.Background(), db, time.Now().Add(time.Second))
+		db.Unscoped().Model(&domain.Note{}).Where("id = ?", noteList[2].ID).Count(&count)
+		require.Equal(t, int64(0), count)
+	})
+}
+
+func TestLongNote(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 1)
+	longBody := strings.Repeat("long note ", 500)
+	note := createTestNote(fmt.Sprintf(`{"title": "Long", "body": "%s", "id_category": %d}`, longBody, categoryList[0].ID))
+
+	t.Run("Note_Long_Get_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"/"+strconv.Itoa(note.ID), http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseGet testNoteJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, longBody, responseGet.Data.Body)
+		require.False(t, responseGet.Data.BodyTruncated)
+	})
+	t.Run("Note_Long_List_Truncated_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseList testNoteListJSON
+		json.Unmarshal(responseBody, &responseList)
+
+		require.Equal(t, http.StatusOK, responseList.Code)
+		require.Equal(t, longBody[:repository.NoteListBodyLength], responseList.Data[0].Body)
+		require.True(t, responseList.Data[0].BodyTruncated)
+		require.LessOrEqual(t, len([]rune(responseList.Data[0].Excerpt)), helper.ExcerptLength+1)
+	})
+	t.Run("Note_Long_Create_TooLarge_Fail", func(t *testing.T) {
+		maxSize := config.GetAppConfig(true).NoteBodyMaxSize
+		requestBody := fmt.Sprintf(`{"title": "Huge", "body": "%s", "id_category": %d}`,
+			strings.Repeat("a", maxSize+1), categoryList[0].ID)
+		request := newTestRequest(noteUrl, http.MethodPost, requestBody)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+
+		responseBody, _ := io.ReadAll(recorder.Result().Body)
+		var responseCreate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseCreate)
+
+		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
+		require.Equal(t, fmt.Sprintf("Body is should below than %d bytes", maxSize), responseCreate.Message)
+	})
+}
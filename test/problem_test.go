@@ -0,0 +1,95 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemDetails(t *testing.T) {
+	t.Run("Problem_Default_Format_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"/9999999", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet web.ErrorResponse
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusNotFound, response.StatusCode)
+		require.Contains(t, response.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+		require.Equal(t, http.StatusNotFound, responseGet.Code)
+		require.Equal(t, "note not found", responseGet.Message)
+	})
+	t.Run("Problem_Accept_NotFound_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"/9999999", http.MethodGet, "")
+		request.Header.Set(echo.HeaderAccept, exception.MIMEApplicationProblemJSON)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var problem web.ProblemDetails
+		json.Unmarshal(responseBody, &problem)
+
+		require.Equal(t, http.StatusNotFound, response.StatusCode)
+		require.Equal(t, exception.MIMEApplicationProblemJSON, response.Header.Get(echo.HeaderContentType))
+		require.Equal(t, "about:blank", problem.Type)
+		require.Equal(t, "Not Found", problem.Title)
+		require.Equal(t, http.StatusNotFound, problem.Status)
+		require.Equal(t, "note not found", problem.Detail)
+		require.Equal(t, "/api/notes/9999999", problem.Instance)
+		require.NotEmpty(t, problem.RequestID)
+		require.Equal(t, response.Header.Get(echo.HeaderXRequestID), problem.RequestID)
+	})
+	t.Run("Problem_Accept_Validation_Success", func(t *testing.T) {
+		request := newTestRequest(categoryUrl, http.MethodPost, `{"name": "a"}`)
+		request.Header.Set(echo.HeaderAccept, exception.MIMEApplicationProblemJSON+", application/json;q=0.5")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var problem web.ProblemDetails
+		json.Unmarshal(responseBody, &problem)
+
+		require.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+		require.Equal(t, "Unprocessable Entity", problem.Title)
+		require.Len(t, problem.Errors, 1)
+		require.Equal(t, "name", problem.Errors[0].Field)
+	})
+	t.Run("Problem_Config_Format_Success", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodDelete, "/api/tags/7", nil)
+		request.Header.Set(echo.HeaderXRequestID, "gateway-request")
+		recorder := httptest.NewRecorder()
+		c := echo.New().NewContext(request, recorder)
+
+		exception.NewErrorHandler(exception.ErrorFormatProblem)(&exception.NotFoundError{Entity: "tag"}, c)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var problem web.ProblemDetails
+		json.Unmarshal(responseBody, &problem)
+
+		require.Equal(t, exception.MIMEApplicationProblemJSON, response.Header.Get(echo.HeaderContentType))
+		require.Equal(t, web.ProblemDetails{
+			Type:      "about:blank",
+			Title:     "Not Found",
+			Status:    http.StatusNotFound,
+			Detail:    "tag not found",
+			Instance:  "/api/tags/7",
+			RequestID: "gateway-request",
+		}, problem)
+	})
+}
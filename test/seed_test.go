@@ -0,0 +1,79 @@
+package test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/app/seed"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	defer deleteAuthTestUsers()
+	defer database.DeleteAllRecords(db)
+	first := database.UserSeeder(db, "auth-seed-first@example.com", "seed-password", domain.RoleEditor)
+	second := database.UserSeeder(db, "auth-seed-second@example.com", "seed-password", domain.RoleEditor)
+
+	t.Run("Seed_Deterministic_Success", func(t *testing.T) {
+		options := seed.Options{Categories: 4, Notes: 40, Distribution: seed.DistributionZipf, Seed: 42}
+		firstResult, errFirst := seed.Run(db, first.ID, options)
+		require.Nil(t, errFirst)
+		secondResult, errSecond := seed.Run(db, second.ID, options)
+		require.Nil(t, errSecond)
+
+		require.Len(t, firstResult.Categories, 4)
+		require.Len(t, firstResult.Notes, 40)
+		for i, category := range firstResult.Categories {
+			require.NotZero(t, category.ID)
+			require.Equal(t, category.Name, secondResult.Categories[i].Name)
+		}
+		for i, note := range firstResult.Notes {
+			require.NotZero(t, note.ID)
+			require.Equal(t, note.Title, secondResult.Notes[i].Title)
+			require.Equal(t, note.Body, secondResult.Notes[i].Body)
+			require.LessOrEqual(t, len(note.Title), seed.TitleMaxLength)
+			require.GreaterOrEqual(t, len(note.Title), 2)
+		}
+
+		var count int64
+		db.Model(&domain.Note{}).Where("user_id = ?", first.ID).Count(&count)
+		require.Equal(t, int64(40), count)
+	})
+	t.Run("Seed_Distribution_Uniform_Success", func(t *testing.T) {
+		result, errRun := seed.Run(db, first.ID, seed.Options{Categories: 3, Notes: 9, Seed: 7})
+		require.Nil(t, errRun)
+
+		for _, category := range result.Categories {
+			var count int64
+			db.Model(&domain.Note{}).Where("category_id = ?", category.ID).Count(&count)
+			require.Equal(t, int64(3), count)
+		}
+	})
+	t.Run("Seed_Fixture_Success", func(t *testing.T) {
+		result, errLoad := seed.LoadFixture(db, seed.Fixtures, "demo", second.ID)
+		require.Nil(t, errLoad)
+		require.Len(t, result.Categories, 3)
+		require.Len(t, result.Notes, 5)
+		require.Equal(t, result.Categories[0].ID, *result.Categories[1].ParentID)
+
+		var tagCount int64
+		db.Model(&domain.NoteTag{}).Where("note_id IN ?", []int{result.Notes[0].ID, result.Notes[4].ID}).Count(&tagCount)
+		require.Equal(t, int64(4), tagCount)
+	})
+	t.Run("Seed_Fixture_Fail", func(t *testing.T) {
+		_, errLoad := seed.LoadFixture(db, seed.Fixtures, "missing", second.ID)
+		require.ErrorIs(t, errLoad, seed.ErrFixtureNotFound)
+
+		fixtures := fstest.MapFS{
+			"broken.yaml": {Data: []byte("categories:\n  - name: Only\nnotes:\n  - title: Lost\n    body: No category\n    category: Other\n")},
+		}
+		_, errLoad = seed.LoadFixture(db, fixtures, "broken", second.ID)
+		require.ErrorContains(t, errLoad, `category "Other" is not listed`)
+
+		var count int64
+		db.Model(&domain.Category{}).Where("user_id = ? AND name = ?", second.ID, "Only").Count(&count)
+		require.Zero(t, count)
+	})
+}
@@ -0,0 +1,204 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/stretchr/testify/require"
+)
+
+type testTagJSON struct {
+	Code   int             `json:"code"`
+	Status string          `json:"status"`
+	Data   web.TagResponse `json:"data"`
+}
+
+type testTagListJSON struct {
+	Code   int               `json:"code"`
+	Status string            `json:"status"`
+	Data   []web.TagResponse `json:"data"`
+}
+
+var tagUrl string = "http://127.0.0.1:8000/api/tags"
+
+func createTestNote(requestBody string) web.NoteResponse {
+	request := newTestRequest(noteUrl, http.MethodPost, requestBody)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+
+	responseBody, _ := io.ReadAll(recorder.Result().Body)
+	var responseCreate testNoteJSON
+	json.Unmarshal(responseBody, &responseCreate)
+
+	return responseCreate.Data
+}
+
+func TestTag(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	var tag web.TagResponse
+
+	t.Run("Tag_Create_Success", func(t *testing.T) {
+		request := newTestRequest(tagUrl, http.MethodPost, `{"name": " Work "}`)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseCreate testTagJSON
+		json.Unmarshal(responseBody, &responseCreate)
+
+		require.Equal(t, http.StatusOK, responseCreate.Code)
+		require.Equal(t, "work", responseCreate.Data.Name)
+		tag = responseCreate.Data
+	})
+	t.Run("Tag_Create_Duplicate_Fail", func(t *testing.T) {
+		request := newTestRequest(tagUrl, http.MethodPost, `{"name": "WORK"}`)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseCreate web.ErrorResponse
+		json.Unmarshal(responseBody, &responseCreate)
+
+		require.Equal(t, http.StatusBadRequest, responseCreate.Code)
+	})
+	t.Run("Tag_Update_Success", func(t *testing.T) {
+		request := newTestRequest(tagUrl+"/"+strconv.Itoa(tag.ID), http.MethodPut, `{"name": "office"}`)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate testTagJSON
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusOK, responseUpdate.Code)
+		require.Equal(t, "office", responseUpdate.Data.Name)
+	})
+	t.Run("Tag_GetAll_Success", func(t *testing.T) {
+		request := newTestRequest(tagUrl, http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testTagListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 1, len(responseGet.Data))
+	})
+	t.Run("Tag_Delete_Success", func(t *testing.T) {
+		request := newTestRequest(tagUrl+"/"+strconv.Itoa(tag.ID), http.MethodDelete, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+	t.Run("Tag_GetById_NotFound_Fail", func(t *testing.T) {
+		request := newTestRequest(tagUrl+"/"+strconv.Itoa(tag.ID), http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+}
+
+func TestNoteTags(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 1)
+	idCategory := categoryList[0].ID
+	both := createTestNote(fmt.Sprintf(`{"title": "Both", "body": "Body", "id_category": %d, "tags": ["Work", "home", "work"]}`, idCategory))
+	createTestNote(fmt.Sprintf(`{"title": "Work", "body": "Body", "id_category": %d, "tags": ["work"]}`, idCategory))
+	createTestNote(fmt.Sprintf(`{"title": "None", "body": "Body", "id_category": %d}`, idCategory))
+	bothUrl := noteUrl + "/" + strconv.Itoa(both.ID)
+
+	t.Run("Note_Create_Tags_Success", func(t *testing.T) {
+		require.Equal(t, []string{"home", "work"}, both.Tags)
+	})
+	t.Run("Note_GetAll_Tag_Any_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?tag=work&tag=home", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testNoteListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 2, len(responseGet.Data))
+	})
+	t.Run("Note_GetAll_Tag_All_Success", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?tag=work&tag=home&tag_match=all", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseGet testNoteListJSON
+		json.Unmarshal(responseBody, &responseGet)
+
+		require.Equal(t, http.StatusOK, responseGet.Code)
+		require.Equal(t, 1, len(responseGet.Data))
+		require.Equal(t, both.ID, responseGet.Data[0].ID)
+	})
+	t.Run("Note_GetAll_TagMatch_Invalid_Fail", func(t *testing.T) {
+		request := newTestRequest(noteUrl+"?tag=work&tag_match=some", http.MethodGet, "")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+	t.Run("Note_Update_Keep_Tags_Success", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"title": "Both edited", "body": "Body", "id_category": %d}`, idCategory)
+		request := newConditionalRequest(bothUrl, http.MethodPut, requestBody, "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate testNoteJSON
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusOK, responseUpdate.Code)
+		require.Equal(t, []string{"home", "work"}, responseUpdate.Data.Tags)
+	})
+	t.Run("Note_Update_Clear_Tags_Success", func(t *testing.T) {
+		requestBody := fmt.Sprintf(`{"title": "Both edited", "body": "Body", "id_category": %d, "tags": []}`, idCategory)
+		request := newConditionalRequest(bothUrl, http.MethodPut, requestBody, "*")
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		response := recorder.Result()
+
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseUpdate testNoteJSON
+		json.Unmarshal(responseBody, &responseUpdate)
+
+		require.Equal(t, http.StatusOK, responseUpdate.Code)
+		require.Equal(t, []string{}, responseUpdate.Data.Tags)
+	})
+}
@@ -1,21 +1,36 @@
 package test
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/naomigrain/echo-crud-notes/app/database"
 	"github.com/naomigrain/echo-crud-notes/app/router"
 	"github.com/naomigrain/echo-crud-notes/config"
+	"github.com/naomigrain/echo-crud-notes/helper"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
 	"gorm.io/gorm"
 )
 
 var e *echo.Echo
 var db *gorm.DB
 var errConn error
+var testUser domain.User
+var testToken string
+
+const testUserPassword = "test-password"
+
+type testTokenJSON struct {
+	Code   int               `json:"code"`
+	Status string            `json:"status"`
+	Data   web.TokenResponse `json:"data"`
+}
 
 func init() {
 	dbConfig := config.GetDBConfig(true)
@@ -24,18 +39,52 @@ func init() {
 		panic(errConn)
 	}
 	database.DropAll(db)
-	database.Migrate(db)
-	database.CategorySeeder(db, 5)
+	migrator, errLoad := database.NewMigrator(db, database.Migrations)
+	if errLoad != nil {
+		panic(errLoad)
+	}
+	if _, errUp := migrator.Up(); errUp != nil {
+		panic(errUp)
+	}
+	testUser = database.UserSeeder(db, "test@example.com", testUserPassword, domain.RoleAdmin)
+	database.CategorySeeder(db, testUser.ID, 5)
+
+	validate := helper.NewValidator()
+
+	appConfig := config.GetAppConfig(true)
+	e = router.InitializeEcho(appConfig)
+	router.AssignRouter(e, db, validate, appConfig)
+
+	testToken = loginTestUser(testUser.Email, testUserPassword).AccessToken
+}
+
+func loginTestUser(email string, password string) web.TokenResponse {
+	requestBody := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)
+	request := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8000/api/auth/login",
+		strings.NewReader(requestBody))
+	request.Header.Add("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
 
-	validate := validator.New()
+	responseBody, _ := io.ReadAll(recorder.Result().Body)
+	var responseLogin testTokenJSON
+	json.Unmarshal(responseBody, &responseLogin)
 
-	e = router.InitializeEcho()
-	router.AssignRouter(e, db, validate)
+	return responseLogin.Data
 }
 
 func newTestRequest(url string, method string, requestBody string) *http.Request {
 	request := httptest.NewRequest(method, url, strings.NewReader(requestBody))
 	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("Authorization", "Bearer "+testToken)
+
+	return request
+}
+
+func newConditionalRequest(url string, method string, requestBody string, ifMatch string) *http.Request {
+	request := newTestRequest(url, method, requestBody)
+	request.Header.Add("If-Match", ifMatch)
 
 	return request
 }
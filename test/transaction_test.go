@@ -0,0 +1,101 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/exception"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/service"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func countTestCategories(name string) int64 {
+	var count int64
+	db.Model(&domain.Category{}).Where("user_id = ? AND name = ?", testUser.ID, name).Count(&count)
+	return count
+}
+
+func TestTxManager(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	txManager := service.NewTxManager(db)
+
+	t.Run("Transaction_Commit_Success", func(t *testing.T) {
+		errTx := txManager.Do(context.Background(), func(tx *gorm.DB) error {
+			return tx.Create(&domain.Category{Name: "tx-commit", UserID: testUser.ID}).Error
+		})
+		require.Nil(t, errTx)
+		require.Equal(t, int64(1), countTestCategories("tx-commit"))
+	})
+	t.Run("Transaction_Error_Rollback", func(t *testing.T) {
+		errWork := errors.New("work failed")
+		errTx := txManager.Do(context.Background(), func(tx *gorm.DB) error {
+			tx.Create(&domain.Category{Name: "tx-error", UserID: testUser.ID})
+			return errWork
+		})
+		require.ErrorIs(t, errTx, errWork)
+		require.Equal(t, int64(0), countTestCategories("tx-error"))
+	})
+	t.Run("Transaction_ErrRollback_Success", func(t *testing.T) {
+		errTx := txManager.Do(context.Background(), func(tx *gorm.DB) error {
+			tx.Create(&domain.Category{Name: "tx-dry-run", UserID: testUser.ID})
+			return service.ErrRollback
+		})
+		require.Nil(t, errTx)
+		require.Equal(t, int64(0), countTestCategories("tx-dry-run"))
+	})
+	t.Run("Transaction_Panic_Rollback", func(t *testing.T) {
+		errTx := txManager.Do(context.Background(), func(tx *gorm.DB) error {
+			tx.Create(&domain.Category{Name: "tx-panic", UserID: testUser.ID})
+			panic("boom")
+		})
+		var errPanic *service.PanicError
+		require.ErrorAs(t, errTx, &errPanic)
+		require.Equal(t, "boom", errPanic.Value)
+		require.Equal(t, int64(0), countTestCategories("tx-panic"))
+	})
+	t.Run("Transaction_Panic_Stack_Logged", func(t *testing.T) {
+		errTx := txManager.Do(context.Background(), func(tx *gorm.DB) error {
+			panic("boom")
+		})
+		var logs bytes.Buffer
+		logged := echo.New()
+		logged.Logger.SetOutput(&logs)
+		c := logged.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+		exception.CustomErrorHandler(errTx, c)
+
+		require.Contains(t, logs.String(), "transaction panicked: boom")
+		require.Contains(t, logs.String(), "goroutine")
+	})
+	t.Run("Transaction_Nested_Rollback", func(t *testing.T) {
+		errTx := txManager.Do(context.Background(), func(tx *gorm.DB) error {
+			if errCreate := tx.Create(&domain.Category{Name: "tx-outer", UserID: testUser.ID}).Error; errCreate != nil {
+				return errCreate
+			}
+			errNested := txManager.Nested(tx, func(tx *gorm.DB) error {
+				tx.Create(&domain.Category{Name: "tx-inner", UserID: testUser.ID})
+				return errors.New("inner failed")
+			})
+			require.NotNil(t, errNested)
+			return nil
+		})
+		require.Nil(t, errTx)
+		require.Equal(t, int64(1), countTestCategories("tx-outer"))
+		require.Equal(t, int64(0), countTestCategories("tx-inner"))
+	})
+	t.Run("Transaction_ReadOnly_Fail", func(t *testing.T) {
+		errTx := txManager.ReadOnly(context.Background(), func(tx *gorm.DB) error {
+			return tx.Create(&domain.Category{Name: "tx-read-only", UserID: testUser.ID}).Error
+		})
+		require.NotNil(t, errTx)
+		require.Equal(t, int64(0), countTestCategories("tx-read-only"))
+	})
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naomigrain/echo-crud-notes/app/database"
+	"github.com/naomigrain/echo-crud-notes/model/domain"
+	"github.com/naomigrain/echo-crud-notes/model/web"
+	"github.com/naomigrain/echo-crud-notes/service"
+	"github.com/stretchr/testify/require"
+)
+
+type testImportJSON struct {
+	Code   int              `json:"code"`
+	Status string           `json:"status"`
+	Data   web.ImportReport `json:"data"`
+}
+
+type testImportConflictJSON struct {
+	Code    int              `json:"code"`
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Details web.ImportReport `json:"details"`
+}
+
+var exportUrl string = "http://127.0.0.1:8000/api/export"
+var importUrl string = "http://127.0.0.1:8000/api/import"
+
+func exportNotes(format string) *http.Response {
+	request := newTestRequest(exportUrl+"?format="+format, http.MethodGet, "")
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+
+	return recorder.Result()
+}
+
+func importNotes(query string, data string) []byte {
+	request := newTestRequest(importUrl+"?"+query, http.MethodPost, data)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+
+	responseBody, _ := io.ReadAll(recorder.Result().Body)
+	return responseBody
+}
+
+func TestExport(t *testing.T) {
+	defer database.DeleteAllRecords(db)
+	categoryList = database.CategorySeeder(db, testUser.ID, 2)
+	notes := database.NoteSeeder(db, categoryList, 3)
+
+	t.Run("Export_JSONL_Success", func(t *testing.T) {
+		response := exportNotes("jsonl")
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "application/x-ndjson", response.Header.Get("Content-Type"))
+
+		responseBody, _ := io.ReadAll(response.Body)
+		lines := strings.Split(strings.TrimSpace(string(responseBody)), "\n")
+		require.Equal(t, len(categoryList)+len(notes), len(lines))
+
+		var first web.TransferRecord
+		json.Unmarshal([]byte(lines[0]), &first)
+		require.Equal(t, web.RecordCategory, first.Type)
+	})
+	t.Run("Export_CSV_Success", func(t *testing.T) {
+		response := exportNotes("csv")
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "text/csv; charset=utf-8", response.Header.Get("Content-Type"))
+
+		responseBody, _ := io.ReadAll(response.Body)
+		lines := strings.Split(strings.TrimSpace(string(responseBody)), "\n")
+		require.True(t, strings.HasPrefix(lines[0], "type,id,"))
+	})
+	t.Run("Export_Markdown_Success", func(t *testing.T) {
+		response := exportNotes("zip-md")
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "application/zip", response.Header.Get("Content-Type"))
+
+		responseBody, _ := io.ReadAll(response.Body)
+		archive, errZip := zip.NewReader(bytes.NewReader(responseBody), int64(len(responseBody)))
+		require.Nil(t, errZip)
+		require.Equal(t, len(notes)+1, len(archive.File))
+	})
+	t.Run("Export_Format_Fail", func(t *testing.T) {
+		response := exportNotes("xml")
+		responseBody, _ := io.ReadAll(response.Body)
+		var responseExport web.ErrorResponse
+		json.Unmarshal(responseBody, &responseExport)
+
+		require.Equal(t, http.StatusBadRequest, responseExport.Code)
+		require.Equal(t, "format is invalid", responseExport.Message)
+	})
+	t.Run("Export_Deadline_Aborted_Fail", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		request := newTestRequest(exportUrl+"?format=jsonl", http.MethodGet, "").WithContext(ctx)
+
+		// The status went out before the export failed, so the connection is
+		// aborted rather than the body ending as if it were complete.
+		require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			e.ServeHTTP(httptest.NewRecorder(), request)
+		})
+	})
+}
+
+func TestImport(t *testing.T) {
+	defer deleteAuthTestUsers()
+	defer database.DeleteAllRecords(db)
+	otherUser := database.UserSeeder(db, "auth-transfer@example.com", "other-password", domain.RoleAdmin)
+	otherNotes := database.NoteSeeder(db, database.CategorySeeder(db, otherUser.ID, 1), 1)
+
+	data := `{"type": "category", "id": 1, "name": "Imported"}
+{"type": "category", "id": 2, "name": "Child", "parent_id": 1}
+{"type": "note", "id": 1, "title": "First", "body": "Body", "category_id": 2, "tags": ["imported"]}
+{"type": "note", "id": 2, "title": "Second", "body": "Body", "category_id": 1}
+`
+
+	t.Run("Import_DryRun_Success", func(t *testing.T) {
+		var responseImport testImportJSON
+		json.Unmarshal(importNotes("dry_run=true", data), &responseImport)
+
+		require.Equal(t, http.StatusOK, responseImport.Code)
+		require.True(t, responseImport.Data.DryRun)
+		require.Equal(t, web.ImportRemapIDs, responseImport.Data.IDs)
+		require.Equal(t, 2, responseImport.Data.Categories.Created)
+		require.Equal(t, 2, responseImport.Data.Notes.Created)
+
+		var count int64
+		db.Model(&domain.Note{}).Where("user_id = ?", testUser.ID).Count(&count)
+		require.Equal(t, int64(0), count)
+	})
+	t.Run("Import_Remap_Success", func(t *testing.T) {
+		var responseImport testImportJSON
+		json.Unmarshal(importNotes("format=jsonl", data), &responseImport)
+
+		require.Equal(t, http.StatusOK, responseImport.Code)
+		require.False(t, responseImport.Data.DryRun)
+		require.Equal(t, 2, responseImport.Data.Notes.Created)
+
+		var child domain.Category
+		db.Where("user_id = ? AND name = ?", testUser.ID, "Child").First(&child)
+		require.NotNil(t, child.ParentID)
+
+		var note domain.Note
+		db.Where("user_id = ? AND title = ?", testUser.ID, "First").First(&note)
+		require.Equal(t, child.ID, note.CategoryID)
+
+		var tagCount int64
+		db.Model(&domain.NoteTag{}).Where("note_id = ?", note.ID).Count(&tagCount)
+		require.Equal(t, int64(1), tagCount)
+	})
+	t.Run("Import_Preserve_Conflict_Fail", func(t *testing.T) {
+		conflict := fmt.Sprintf(`{"type": "category", "id": %d, "name": "Taken"}
+{"type": "note", "id": %d, "title": "Taken", "body": "Body", "category_id": %d}
+`, otherNotes[0].CategoryID, otherNotes[0].ID, otherNotes[0].CategoryID)
+		var responseImport testImportConflictJSON
+		json.Unmarshal(importNotes("ids=preserve", conflict), &responseImport)
+
+		require.Equal(t, http.StatusConflict, responseImport.Code)
+		require.Equal(t, "import has conflicts", responseImport.Message)
+		require.Equal(t, 2, len(responseImport.Details.Conflicts))
+
+		var note domain.Note
+		db.First(&note, otherNotes[0].ID)
+		require.Equal(t, otherNotes[0].Title, note.Title)
+	})
+	t.Run("Import_Parse_Fail", func(t *testing.T) {
+		var responseImport web.ErrorResponse
+		json.Unmarshal(importNotes("format=jsonl", "not json"), &responseImport)
+
+		require.Equal(t, http.StatusBadRequest, responseImport.Code)
+	})
+	t.Run("Import_Markdown_TooLarge_Fail", func(t *testing.T) {
+		// Each file fits in the limit on its own and compresses to a few KB,
+		// but together they unpack to more than an import may be.
+		var archive bytes.Buffer
+		writer := zip.NewWriter(&archive)
+		body := strings.Repeat("a", 12<<20)
+		for i := 0; i < 3; i++ {
+			file, errCreate := writer.Create(fmt.Sprintf("notes/%d.md", i))
+			require.Nil(t, errCreate)
+			_, errWrite := file.Write([]byte("---\ntitle: \"Large\"\n---\n" + body))
+			require.Nil(t, errWrite)
+		}
+		require.Nil(t, writer.Close())
+
+		var responseImport web.ErrorResponse
+		json.Unmarshal(importNotes("format=zip-md&dry_run=true", archive.String()), &responseImport)
+
+		require.Equal(t, http.StatusUnprocessableEntity, responseImport.Code)
+		require.Equal(t, "archive can be at most 32 MB unpacked", responseImport.Message)
+	})
+	t.Run("Import_Markdown_TooManyFiles_Fail", func(t *testing.T) {
+		var archive bytes.Buffer
+		writer := zip.NewWriter(&archive)
+		for i := 0; i <= service.ImportMaxFiles; i++ {
+			_, errCreate := writer.Create(fmt.Sprintf("notes/%d.md", i))
+			require.Nil(t, errCreate)
+		}
+		require.Nil(t, writer.Close())
+
+		var responseImport web.ErrorResponse
+		json.Unmarshal(importNotes("format=zip-md&dry_run=true", archive.String()), &responseImport)
+
+		require.Equal(t, http.StatusUnprocessableEntity, responseImport.Code)
+		require.Equal(t, "archive can have at most 10000 files", responseImport.Message)
+	})
+}